@@ -2,6 +2,9 @@ package main
 
 import (
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -12,6 +15,7 @@ import (
 	"github.com/novianakbar/livechat-be/internal/delivery/routes"
 	"github.com/novianakbar/livechat-be/internal/infrastructure/database"
 	"github.com/novianakbar/livechat-be/internal/infrastructure/email"
+	"github.com/novianakbar/livechat-be/internal/infrastructure/oidc"
 	"github.com/novianakbar/livechat-be/internal/infrastructure/repository"
 	"github.com/novianakbar/livechat-be/internal/service"
 	"github.com/novianakbar/livechat-be/internal/usecase"
@@ -46,15 +50,57 @@ func main() {
 	sessionContactRepo := repository.NewChatSessionContactRepository(db)
 	agentStatusRepo := repository.NewAgentStatusRepository(redisClient)
 	agentSessionRepo := repository.NewAgentSessionRepository(db)
+	departmentRepo := repository.NewDepartmentRepository(db)
+	chatAnalyticsRepo := repository.NewChatAnalyticsRepository(db)
+	topicMappingRepo := repository.NewTopicDepartmentMappingRepository(db)
+	waitingQueueRepo := repository.NewWaitingQueueRepository(redisClient)
+	sessionRatingRepo := repository.NewSessionRatingRepository(db)
+	tokenDenylistRepo := repository.NewTokenDenylistRepository(redisClient)
+	refreshFamilyRepo := repository.NewRefreshTokenFamilyRepository(redisClient)
+	loginSessionRepo := repository.NewLoginSessionRepository(redisClient)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(redisClient)
+	passwordResetTokenRepo := repository.NewPasswordResetTokenRepository(db)
+	userMFARepo := repository.NewUserMFARepository(db)
+	recoveryCodeRepo := repository.NewUserRecoveryCodeRepository(db)
+	mfaPolicyRepo := repository.NewMFAPolicyRepository(db)
+	oidcStateRepo := repository.NewOIDCStateRepository(redisClient)
+	permissionRepo := repository.NewPermissionRepository(db)
+	tagRepo := repository.NewChatTagRepository(db)
+	sessionTagRepo := repository.NewChatSessionTagRepository(db)
+
+	// Initialize agent assignment
+	assignmentStrategy := service.NewAssignmentStrategy(cfg.Assignment.Strategy, redisClient, cfg.Assignment.DepartmentWeights, cfg.Assignment.MaxSessionsPerAgent)
+	agentAssignmentService := service.NewAgentAssignmentService(userRepo, sessionRepo, agentStatusRepo, assignmentStrategy, cfg.Assignment.MaxSessionsPerAgent)
+	queueService := service.NewQueueService(waitingQueueRepo, sessionRepo, agentStatusRepo, cfg.Assignment.MaxSessionsPerAgent)
+	loginThrottleService := service.NewLoginThrottleService(loginAttemptRepo, cfg.Login.MaxAttempts, cfg.Login.IPMaxAttempts, cfg.Login.FailureWindow, cfg.Login.BaseLockout, cfg.Login.MaxLockout)
 
 	// Initialize use cases
-	authUsecase := usecase.NewAuthUsecase(userRepo, agentSessionRepo, jwtUtil)
-	chatUsecase := usecase.NewChatUsecase(sessionRepo, messageRepo, userRepo, logRepo, chatUserRepo, sessionContactRepo)
-	analyticsUsecase := usecase.NewAnalyticsUsecase(sessionRepo, messageRepo, userRepo)
-	userUsecase := usecase.NewUserUsecase(userRepo)
+	mfaUsecase := usecase.NewMFAUsecase(userRepo, userMFARepo, recoveryCodeRepo, mfaPolicyRepo, cfg.Auth.MFAIssuer)
+	authUsecase := usecase.NewAuthUsecase(userRepo, agentSessionRepo, tokenDenylistRepo, refreshFamilyRepo, loginSessionRepo, loginThrottleService, mfaUsecase, jwtUtil, cfg.Auth.MFAChallengeTTL)
+	permissionUsecase := usecase.NewPermissionUsecase(permissionRepo)
+	chatUsecase := usecase.NewChatUsecase(sessionRepo, messageRepo, userRepo, logRepo, chatUserRepo, sessionContactRepo, departmentRepo, topicMappingRepo, sessionRatingRepo, agentAssignmentService, queueService, permissionUsecase, jwtUtil, cfg.Auth.CustomerTokenTTL)
+	analyticsUsecase := usecase.NewAnalyticsUsecase(sessionRepo, messageRepo, userRepo, sessionRatingRepo, chatAnalyticsRepo, departmentRepo)
+	userUsecase := usecase.NewUserUsecase(userRepo, departmentRepo, authUsecase, chatUsecase)
+	departmentUsecase := usecase.NewDepartmentUsecase(departmentRepo, sessionRepo)
+	tagUsecase := usecase.NewTagUsecase(tagRepo, sessionTagRepo, sessionRepo)
+
+	// SSO stays disabled without an identity provider
+	var oidcProvider usecase.OIDCProvider
+	if cfg.OIDC.Enabled() {
+		oidcProvider = oidc.NewProvider(cfg.OIDC.IssuerURL, cfg.OIDC.ClientID, cfg.OIDC.ClientSecret, cfg.OIDC.RedirectURL, cfg.OIDC.Scopes)
+	}
+	ssoUsecase := usecase.NewSSOUsecase(oidcProvider, oidcStateRepo, authUsecase, userRepo, departmentRepo, usecase.SSOClaimMapping{
+		EmailClaim:           cfg.OIDC.EmailClaim,
+		GroupsClaim:          cfg.OIDC.GroupsClaim,
+		RoleMapping:          cfg.OIDC.RoleMapping,
+		DepartmentMapping:    cfg.OIDC.DepartmentMapping,
+		JITProvisioning:      cfg.OIDC.JITProvisioning,
+		AllowUnverifiedEmail: cfg.OIDC.AllowUnverifiedEmail,
+	}, cfg.OIDC.StateTTL)
 
 	// Initialize email service
 	emailService := email.NewSendGridService(&cfg.Email)
+	passwordResetUsecase := usecase.NewPasswordResetUsecase(userRepo, passwordResetTokenRepo, emailService, authUsecase, cfg.Auth.PasswordResetTokenTTL)
 
 	// Initialize Kafka service
 	kafkaService := service.NewKafkaService()
@@ -62,16 +108,30 @@ func main() {
 	// Initialize agent status service
 	agentStatusService := service.NewAgentStatusService(agentStatusRepo, userRepo)
 
+	// Initialize background workers
+	assignmentWorker := service.NewAssignmentWorker(chatUsecase, cfg.Assignment.RetryInterval)
+	agentStatusService.AddHeartbeatListener(assignmentWorker)
+
+	idleSessionJob := service.NewIdleSessionJob(chatUsecase, kafkaService, cfg.Idle.WaitingTimeout, cfg.Idle.ActiveTimeout, cfg.Idle.WarningBefore)
+	jobScheduler := service.NewJobScheduler()
+	jobScheduler.Every("idle-session-sweeper", cfg.Idle.SweepInterval, idleSessionJob.Run)
+	jobScheduler.Daily("analytics-rollup", cfg.Analytics.RollupTime, analyticsUsecase.RunDailyRollup)
+
 	// Initialize handlers
-	authHandler := handler.NewAuthHandler(authUsecase)
-	chatHandler := handler.NewChatHandler(chatUsecase, kafkaService)
+	authHandler := handler.NewAuthHandler(authUsecase, passwordResetUsecase, ssoUsecase)
+	chatHandler := handler.NewChatHandler(chatUsecase, tagUsecase, kafkaService)
 	analyticsHandler := handler.NewAnalyticsHandler(analyticsUsecase)
 	userHandler := handler.NewUserHandler(userUsecase)
 	emailHandler := handler.NewEmailHandler(emailService)
 	agentStatusHandler := handler.NewAgentStatusHandler(agentStatusService)
+	mfaHandler := handler.NewMFAHandler(mfaUsecase)
+	permissionHandler := handler.NewPermissionHandler(permissionUsecase)
+	departmentHandler := handler.NewDepartmentHandler(departmentUsecase)
+	tagHandler := handler.NewTagHandler(tagUsecase)
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(authUsecase)
+	authMiddleware := middleware.NewAuthMiddleware(authUsecase, permissionUsecase)
+	customerMiddleware := middleware.NewCustomerMiddleware(chatUsecase)
 
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
@@ -102,12 +162,34 @@ func main() {
 	}))
 
 	// Setup routes (tanpa wsHandler)
-	routes.SetupRoutes(app, authHandler, chatHandler, analyticsHandler, userHandler, emailHandler, agentStatusHandler, authMiddleware)
+	routes.SetupRoutes(app, authHandler, chatHandler, analyticsHandler, userHandler, emailHandler, agentStatusHandler, mfaHandler, permissionHandler, departmentHandler, tagHandler, authMiddleware, customerMiddleware)
+
+	// Start background workers
+	assignmentWorker.Start()
+	jobScheduler.Start()
 
 	// Start server
 	serverAddr := cfg.Server.Host + ":" + cfg.Server.Port
-	log.Printf("Server starting on %s", serverAddr)
-	log.Fatal(app.Listen(serverAddr))
+	go func() {
+		log.Printf("Server starting on %s", serverAddr)
+		if err := app.Listen(serverAddr); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	// Wait for shutdown signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down server...")
+	if err := app.Shutdown(); err != nil {
+		log.Printf("Server shutdown error: %v", err)
+	}
+
+	// Stop background workers
+	assignmentWorker.Stop()
+	jobScheduler.Stop()
 
 	// Close connections
 	if redisClient != nil {
@@ -14,6 +14,7 @@ require (
 	golang.org/x/crypto v0.33.0
 	gorm.io/driver/postgres v1.5.4
 	gorm.io/gorm v1.25.5
+	gorm.io/plugin/soft_delete v1.2.1
 )
 
 require (
@@ -40,7 +41,6 @@ require (
 	github.com/valyala/tcplisten v1.0.0 // indirect
 	golang.org/x/sys v0.30.0 // indirect
 	golang.org/x/text v0.22.0 // indirect
-	gorm.io/plugin/soft_delete v1.2.1 // indirect
 )
 
 replace github.com/novianakbar/livechat-shared => ../livechat-shared
@@ -1,9 +1,14 @@
 package handler
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/novianakbar/livechat-be/internal/domain"
 	"github.com/novianakbar/livechat-be/internal/usecase"
+	"github.com/novianakbar/livechat-be/pkg/utils"
 )
 
 type AnalyticsHandler struct {
@@ -23,12 +28,24 @@ func NewAnalyticsHandler(analyticsUsecase *usecase.AnalyticsUsecase) *AnalyticsH
 // @Accept json
 // @Produce json
 // @Security Bearer
+// @Param start_date query string false "Start date (YYYY-MM-DD), defaults to 30 days ago"
+// @Param end_date query string false "End date (YYYY-MM-DD), inclusive"
 // @Success 200 {object} domain.ApiResponse{data=domain.DashboardStats}
+// @Failure 400 {object} domain.ApiResponse
 // @Failure 401 {object} domain.ApiResponse
 // @Failure 500 {object} domain.ApiResponse
 // @Router /api/analytics/dashboard [get]
 func (h *AnalyticsHandler) GetDashboardStats(c *fiber.Ctx) error {
-	stats, err := h.analyticsUsecase.GetDashboardStats()
+	start, end, err := parseDateRange(optionalQuery(c, "start_date"), optionalQuery(c, "end_date"), 30)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid date range",
+			Error:   err.Error(),
+		})
+	}
+
+	stats, err := h.analyticsUsecase.GetDashboardStats(c.Context(), start, end)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
 			Success: false,
@@ -46,16 +63,17 @@ func (h *AnalyticsHandler) GetDashboardStats(c *fiber.Ctx) error {
 
 // GetAnalytics godoc
 // @Summary Get analytics data
-// @Description Get detailed analytics data with filtering options
+// @Description Get daily, agent and department analytics from the nightly rollup, with today aggregated live. Defaults to the last 30 days.
 // @Tags Analytics
 // @Accept json
 // @Produce json
 // @Security Bearer
 // @Param start_date query string false "Start date (YYYY-MM-DD)"
-// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD), inclusive"
 // @Param department_id query string false "Department ID"
 // @Param agent_id query string false "Agent ID"
-// @Success 200 {object} domain.ApiResponse{data=[]domain.ChatAnalytics}
+// @Success 200 {object} domain.ApiResponse{data=domain.AnalyticsResponse}
+// @Failure 400 {object} domain.ApiResponse
 // @Failure 401 {object} domain.ApiResponse
 // @Failure 500 {object} domain.ApiResponse
 // @Router /api/analytics [get]
@@ -69,7 +87,46 @@ func (h *AnalyticsHandler) GetAnalytics(c *fiber.Ctx) error {
 		})
 	}
 
-	analytics, err := h.analyticsUsecase.GetAnalytics(&req)
+	start, end, err := parseDateRange(req.StartDate, req.EndDate, 30)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid date range",
+			Error:   err.Error(),
+		})
+	}
+
+	analyticsReq := &domain.AnalyticsRequest{
+		StartDate: start,
+		// parseDateRange returns an exclusive end, the rollup works on whole days
+		EndDate: end.AddDate(0, 0, -1),
+	}
+
+	if req.AgentID != nil && *req.AgentID != "" {
+		agentID, err := uuid.Parse(*req.AgentID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Invalid agent ID",
+				Error:   err.Error(),
+			})
+		}
+		analyticsReq.AgentID = &agentID
+	}
+
+	if req.DepartmentID != nil && *req.DepartmentID != "" {
+		departmentID, err := uuid.Parse(*req.DepartmentID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Invalid department ID",
+				Error:   err.Error(),
+			})
+		}
+		analyticsReq.DepartmentID = &departmentID
+	}
+
+	analytics, err := h.analyticsUsecase.GetAnalytics(c.Context(), analyticsReq)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
 			Success: false,
@@ -84,3 +141,151 @@ func (h *AnalyticsHandler) GetAnalytics(c *fiber.Ctx) error {
 		Data:    analytics,
 	})
 }
+
+// GetAgentPerformance godoc
+// @Summary Get agent performance
+// @Description Get sessions, messages and CSAT ratings per agent. Defaults to the last 30 days.
+// @Tags Analytics
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD), inclusive"
+// @Success 200 {object} domain.ApiResponse{data=[]domain.AgentPerformance}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Router /api/analytics/agent-performance [get]
+func (h *AnalyticsHandler) GetAgentPerformance(c *fiber.Ctx) error {
+	var req domain.GetAnalyticsRequest
+	if err := c.QueryParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid query parameters",
+			Error:   err.Error(),
+		})
+	}
+
+	start, end, err := parseDateRange(req.StartDate, req.EndDate, 30)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid date range",
+			Error:   err.Error(),
+		})
+	}
+
+	performance, err := h.analyticsUsecase.GetAgentPerformance(c.Context(), start, end)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to get agent performance",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Agent performance retrieved successfully",
+		Data:    performance,
+	})
+}
+
+// GetSessionTimings godoc
+// @Summary Get session timings
+// @Description Get average first response time and average handle time in seconds. Defaults to the last 30 days.
+// @Tags Analytics
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD), inclusive"
+// @Param department_id query string false "Department ID"
+// @Param agent_id query string false "Agent ID"
+// @Success 200 {object} domain.ApiResponse{data=domain.SessionTimingStats}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Router /api/analytics/response-times [get]
+func (h *AnalyticsHandler) GetSessionTimings(c *fiber.Ctx) error {
+	var req domain.GetAnalyticsRequest
+	if err := c.QueryParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid query parameters",
+			Error:   err.Error(),
+		})
+	}
+
+	start, end, err := parseDateRange(req.StartDate, req.EndDate, 30)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid date range",
+			Error:   err.Error(),
+		})
+	}
+
+	filter := domain.SessionMetricsFilter{
+		StartDate: &start,
+		EndDate:   &end,
+	}
+	if req.DepartmentID != nil && *req.DepartmentID != "" {
+		filter.DepartmentID = req.DepartmentID
+	}
+	if req.AgentID != nil && *req.AgentID != "" {
+		filter.AgentID = req.AgentID
+	}
+
+	timings, err := h.analyticsUsecase.GetSessionTimings(c.Context(), filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to get session timings",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Session timings retrieved successfully",
+		Data:    timings,
+	})
+}
+
+// optionalQuery returns the query parameter or nil when it is empty
+func optionalQuery(c *fiber.Ctx, key string) *string {
+	if value := c.Query(key); value != "" {
+		return &value
+	}
+	return nil
+}
+
+// parseDateRange parses YYYY-MM-DD query dates into a half-open [start, end)
+// range. Missing dates default to the last defaultDays days up to today.
+func parseDateRange(startDate, endDate *string, defaultDays int) (time.Time, time.Time, error) {
+	today := utils.StartOfDay(time.Now())
+	end := today.AddDate(0, 0, 1)
+	if endDate != nil && *endDate != "" {
+		parsed, err := utils.ParseDate(*endDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end_date: %w", err)
+		}
+		end = parsed.AddDate(0, 0, 1)
+	}
+
+	start := end.AddDate(0, 0, -defaultDays)
+	if startDate != nil && *startDate != "" {
+		parsed, err := utils.ParseDate(*startDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid start_date: %w", err)
+		}
+		start = parsed
+	}
+
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start_date must not be after end_date")
+	}
+
+	return start, end, nil
+}
@@ -1,18 +1,28 @@
 package handler
 
 import (
+	"errors"
+	"math"
+	"strconv"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/delivery/middleware"
 	"github.com/novianakbar/livechat-be/internal/domain"
 	"github.com/novianakbar/livechat-be/internal/usecase"
 )
 
 type AuthHandler struct {
-	authUsecase *usecase.AuthUsecase
+	authUsecase          *usecase.AuthUsecase
+	passwordResetUsecase *usecase.PasswordResetUsecase
+	ssoUsecase           *usecase.SSOUsecase
 }
 
-func NewAuthHandler(authUsecase *usecase.AuthUsecase) *AuthHandler {
+func NewAuthHandler(authUsecase *usecase.AuthUsecase, passwordResetUsecase *usecase.PasswordResetUsecase, ssoUsecase *usecase.SSOUsecase) *AuthHandler {
 	return &AuthHandler{
-		authUsecase: authUsecase,
+		authUsecase:          authUsecase,
+		passwordResetUsecase: passwordResetUsecase,
+		ssoUsecase:           ssoUsecase,
 	}
 }
 
@@ -26,6 +36,7 @@ func NewAuthHandler(authUsecase *usecase.AuthUsecase) *AuthHandler {
 // @Success 200 {object} domain.ApiResponse{data=domain.LoginResponse}
 // @Failure 400 {object} domain.ApiResponse
 // @Failure 401 {object} domain.ApiResponse
+// @Failure 429 {object} domain.ApiResponse
 // @Router /api/auth/login [post]
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	var req domain.LoginRequest
@@ -52,6 +63,16 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 
 	response, err := h.authUsecase.Login(c.Context(), &req, clientIP, userAgent)
 	if err != nil {
+		var lockedErr *usecase.LoginLockedError
+		if errors.As(err, &lockedErr) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			return c.Status(fiber.StatusTooManyRequests).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Login failed",
+				Error:   err.Error(),
+			})
+		}
+
 		return c.Status(fiber.StatusUnauthorized).JSON(domain.ApiResponse{
 			Success: false,
 			Message: "Login failed",
@@ -59,15 +80,173 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
+	if response.MFARequired {
+		return c.JSON(domain.ApiResponse{
+			Success: true,
+			Message: "MFA verification required",
+			Data:    response,
+		})
+	}
+
 	// Set secure cookie for refresh token
-	c.Cookie(&fiber.Cookie{
-		Name:     "refresh_token",
-		Value:    response.RefreshToken,
-		MaxAge:   7 * 24 * 60 * 60, // 7 days
-		HTTPOnly: true,
-		Secure:   true,
-		SameSite: "Strict",
+	setRefreshTokenCookie(c, response.RefreshToken)
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Login successful",
+		Data:    response,
 	})
+}
+
+// CompleteMFALogin godoc
+// @Summary Complete login with MFA
+// @Description Finish a login that returned mfa_required with the MFA token and a TOTP or recovery code
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body domain.MFALoginRequest true "MFA login request"
+// @Success 200 {object} domain.ApiResponse{data=domain.LoginResponse}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 429 {object} domain.ApiResponse
+// @Router /api/auth/login/mfa [post]
+func (h *AuthHandler) CompleteMFALogin(c *fiber.Ctx) error {
+	var req domain.MFALoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	if req.MFAToken == "" || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "MFA token and code are required",
+			Error:   "validation failed",
+		})
+	}
+
+	response, err := h.authUsecase.CompleteMFALogin(c.Context(), &req, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		var lockedErr *usecase.LoginLockedError
+		if errors.As(err, &lockedErr) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			return c.Status(fiber.StatusTooManyRequests).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Login failed",
+				Error:   err.Error(),
+			})
+		}
+
+		return c.Status(fiber.StatusUnauthorized).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Login failed",
+			Error:   err.Error(),
+		})
+	}
+
+	setRefreshTokenCookie(c, response.RefreshToken)
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Login successful",
+		Data:    response,
+	})
+}
+
+// SSOAuthorize godoc
+// @Summary Start SSO login
+// @Description Start an OpenID Connect login (authorization code with PKCE). Send the browser to authorization_url; the IdP redirects back to the frontend with code and state.
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} domain.ApiResponse{data=domain.SSOAuthorizeResponse}
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 502 {object} domain.ApiResponse
+// @Router /api/auth/sso/authorize [get]
+func (h *AuthHandler) SSOAuthorize(c *fiber.Ctx) error {
+	response, err := h.ssoUsecase.Authorize(c.Context())
+	if err != nil {
+		status := fiber.StatusBadGateway
+		if err.Error() == "sso is not configured" {
+			status = fiber.StatusNotFound
+		}
+
+		return c.Status(status).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to start SSO login",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Redirect to the identity provider",
+		Data:    response,
+	})
+}
+
+// SSOCallback godoc
+// @Summary Complete SSO login
+// @Description Exchange the code and state the IdP redirected back with for tokens. Users with MFA enabled get mfa_required like a password login.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body domain.SSOCallbackRequest true "SSO callback request"
+// @Success 200 {object} domain.ApiResponse{data=domain.LoginResponse}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 403 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Router /api/auth/sso/callback [post]
+func (h *AuthHandler) SSOCallback(c *fiber.Ctx) error {
+	var req domain.SSOCallbackRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	if req.Code == "" || req.State == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Code and state are required",
+			Error:   "validation failed",
+		})
+	}
+
+	response, err := h.ssoUsecase.Callback(c.Context(), &req, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		status := fiber.StatusUnauthorized
+		switch err.Error() {
+		case "sso is not configured":
+			status = fiber.StatusNotFound
+		case "invalid or expired sso state":
+			status = fiber.StatusBadRequest
+		case "user account is inactive", "no account exists for this sso user",
+			"sso user has no role", "sso supervisor has no department":
+			status = fiber.StatusForbidden
+		}
+
+		return c.Status(status).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "SSO login failed",
+			Error:   err.Error(),
+		})
+	}
+
+	if response.MFARequired {
+		return c.JSON(domain.ApiResponse{
+			Success: true,
+			Message: "MFA verification required",
+			Data:    response,
+		})
+	}
+
+	setRefreshTokenCookie(c, response.RefreshToken)
 
 	return c.JSON(domain.ApiResponse{
 		Success: true,
@@ -107,10 +286,10 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		})
 	}
 
-	if req.Role != "admin" && req.Role != "agent" {
+	if req.Role != "admin" && req.Role != "supervisor" && req.Role != "agent" {
 		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
 			Success: false,
-			Message: "Role must be 'admin' or 'agent'",
+			Message: "Role must be 'admin', 'supervisor' or 'agent'",
 			Error:   "validation failed",
 		})
 	}
@@ -118,8 +297,11 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 	user, err := h.authUsecase.Register(c.Context(), &req)
 	if err != nil {
 		status := fiber.StatusInternalServerError
-		if err.Error() == "user with this email already exists" {
+		switch err.Error() {
+		case "user with this email already exists":
 			status = fiber.StatusConflict
+		case "supervisor must belong to a department":
+			status = fiber.StatusBadRequest
 		}
 		return c.Status(status).JSON(domain.ApiResponse{
 			Success: false,
@@ -137,7 +319,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 
 // RefreshToken godoc
 // @Summary Refresh access token
-// @Description Generate new access token using refresh token
+// @Description Exchange a refresh token for a new token pair. Refresh tokens are single-use; reusing one revokes all tokens of that login. Falls back to the refresh_token cookie when the body has none.
 // @Tags Authentication
 // @Accept json
 // @Produce json
@@ -148,12 +330,18 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 // @Router /api/auth/refresh [post]
 func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
 	var req domain.RefreshTokenRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
-			Success: false,
-			Message: "Invalid request body",
-			Error:   err.Error(),
-		})
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Invalid request body",
+				Error:   err.Error(),
+			})
+		}
+	}
+
+	if req.RefreshToken == "" {
+		req.RefreshToken = c.Cookies("refresh_token")
 	}
 
 	// Validate request
@@ -165,7 +353,7 @@ func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
 		})
 	}
 
-	response, err := h.authUsecase.RefreshToken(c.Context(), &req)
+	response, err := h.authUsecase.RefreshToken(c.Context(), &req, c.IP(), c.Get("User-Agent"))
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(domain.ApiResponse{
 			Success: false,
@@ -174,6 +362,9 @@ func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
 		})
 	}
 
+	// The old refresh token is used up, replace the cookie
+	setRefreshTokenCookie(c, response.RefreshToken)
+
 	return c.JSON(domain.ApiResponse{
 		Success: true,
 		Message: "Token refreshed successfully",
@@ -286,3 +477,401 @@ func (h *AuthHandler) ValidateSession(c *fiber.Ctx) error {
 		Data:    user,
 	})
 }
+
+// RevokeUserTokens godoc
+// @Summary Revoke all tokens of a user
+// @Description Revoke every access and refresh token issued to the user so far (admin only). The user has to log in again.
+// @Tags Authentication
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} domain.ApiResponse
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/auth/users/{id}/revoke-tokens [post]
+func (h *AuthHandler) RevokeUserTokens(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid user ID",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := h.authUsecase.RevokeAllTokens(c.Context(), userID.String()); err != nil {
+		status := fiber.StatusInternalServerError
+		if err.Error() == "user not found" {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to revoke tokens",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "All tokens of the user have been revoked",
+	})
+}
+
+// GetSessions godoc
+// @Summary List my sessions
+// @Description List the devices the current user is signed in on. The session of this request is marked as current.
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} domain.ApiResponse{data=[]domain.LoginSession}
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/auth/sessions [get]
+func (h *AuthHandler) GetSessions(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "User not found in context",
+			Error:   "authentication required",
+		})
+	}
+
+	sessions, err := h.authUsecase.GetSessions(c.Context(), user.ID, middleware.GetSessionIDFromContext(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to get sessions",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Sessions retrieved successfully",
+		Data:    sessions,
+	})
+}
+
+// TerminateSession godoc
+// @Summary Terminate one of my sessions
+// @Description Sign the current user out of one device. Its access and refresh tokens stop working immediately.
+// @Tags Authentication
+// @Produce json
+// @Param session_id path string true "Session ID"
+// @Success 200 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/auth/sessions/{session_id} [delete]
+func (h *AuthHandler) TerminateSession(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "User not found in context",
+			Error:   "authentication required",
+		})
+	}
+
+	return h.terminateSession(c, user.ID, c.Params("session_id"))
+}
+
+// GetUserSessions godoc
+// @Summary List sessions of a user
+// @Description List the devices a user is signed in on (admin only)
+// @Tags Authentication
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} domain.ApiResponse{data=[]domain.LoginSession}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/auth/users/{id}/sessions [get]
+func (h *AuthHandler) GetUserSessions(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid user ID",
+			Error:   err.Error(),
+		})
+	}
+
+	sessions, err := h.authUsecase.GetSessions(c.Context(), userID.String(), middleware.GetSessionIDFromContext(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to get sessions",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Sessions retrieved successfully",
+		Data:    sessions,
+	})
+}
+
+// TerminateUserSession godoc
+// @Summary Terminate a session of a user
+// @Description Sign a user out of one device (admin only)
+// @Tags Authentication
+// @Produce json
+// @Param id path string true "User ID"
+// @Param session_id path string true "Session ID"
+// @Success 200 {object} domain.ApiResponse
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/auth/users/{id}/sessions/{session_id} [delete]
+func (h *AuthHandler) TerminateUserSession(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid user ID",
+			Error:   err.Error(),
+		})
+	}
+
+	return h.terminateSession(c, userID.String(), c.Params("session_id"))
+}
+
+func (h *AuthHandler) terminateSession(c *fiber.Ctx, userID, sessionID string) error {
+	if err := h.authUsecase.TerminateSession(c.Context(), userID, sessionID); err != nil {
+		status := fiber.StatusInternalServerError
+		if err.Error() == "session not found" {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to terminate session",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Session terminated successfully",
+	})
+}
+
+// UnlockUser godoc
+// @Summary Unlock a user account
+// @Description Lift the temporary login lockout of a user after too many failed logins (admin only)
+// @Tags Authentication
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} domain.ApiResponse
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/auth/users/{id}/unlock [post]
+func (h *AuthHandler) UnlockUser(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid user ID",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := h.authUsecase.UnlockUser(c.Context(), userID.String()); err != nil {
+		status := fiber.StatusInternalServerError
+		if err.Error() == "user not found" {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to unlock user",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "User unlocked successfully",
+	})
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Email a single-use password reset link. The response is the same whether or not the email belongs to an account.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body domain.ForgotPasswordRequest true "Forgot password request"
+// @Success 200 {object} domain.ApiResponse
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Router /api/auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req domain.ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	if req.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Email is required",
+			Error:   "validation failed",
+		})
+	}
+
+	if err := h.passwordResetUsecase.ForgotPassword(c.Context(), req.Email); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to request password reset",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "If an account exists for this email, a password reset link has been sent",
+	})
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Set a new password with a reset token. The token can be used once and all existing sessions of the user are signed out.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body domain.ResetPasswordRequest true "Reset password request"
+// @Success 200 {object} domain.ApiResponse
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Router /api/auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req domain.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	if req.Token == "" || req.NewPassword == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Token and new password are required",
+			Error:   "validation failed",
+		})
+	}
+
+	if err := h.passwordResetUsecase.ResetPassword(c.Context(), &req); err != nil {
+		status := fiber.StatusInternalServerError
+		switch err.Error() {
+		case "invalid or expired reset token", usecase.ErrPasswordTooShort.Error():
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Password reset failed",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Password has been reset, please log in again",
+	})
+}
+
+// ChangePassword godoc
+// @Summary Change my password
+// @Description Set a new password with the current one. All sessions of the user, including this one, are signed out.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body domain.ChangePasswordRequest true "Change password request"
+// @Success 200 {object} domain.ApiResponse
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/auth/change-password [post]
+func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
+	userID := middleware.GetUserIDFromContext(c)
+	if userID == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "User not found in context",
+			Error:   "authentication required",
+		})
+	}
+
+	var req domain.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Current and new password are required",
+			Error:   "validation failed",
+		})
+	}
+
+	if err := h.authUsecase.ChangePassword(c.Context(), *userID, &req); err != nil {
+		status := fiber.StatusInternalServerError
+		switch err.Error() {
+		case "invalid current password", usecase.ErrPasswordTooShort.Error():
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to change password",
+			Error:   err.Error(),
+		})
+	}
+
+	// Clear refresh token cookie
+	c.Cookie(&fiber.Cookie{
+		Name:     "refresh_token",
+		Value:    "",
+		MaxAge:   -1,
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: "Strict",
+	})
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Password changed, please log in again",
+	})
+}
+
+func setRefreshTokenCookie(c *fiber.Ctx, refreshToken string) {
+	c.Cookie(&fiber.Cookie{
+		Name:     "refresh_token",
+		Value:    refreshToken,
+		MaxAge:   7 * 24 * 60 * 60, // 7 days
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: "Strict",
+	})
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"strconv"
 	"time"
@@ -12,18 +14,21 @@ import (
 	"github.com/novianakbar/livechat-be/internal/mappers"
 	"github.com/novianakbar/livechat-be/internal/service"
 	"github.com/novianakbar/livechat-be/internal/usecase"
+	"github.com/novianakbar/livechat-be/pkg/utils"
 )
 
 // ChatHandler handles chat-related endpoints.
 type ChatHandler struct {
 	chatUsecase  *usecase.ChatUsecase
+	tagUsecase   *usecase.TagUsecase
 	kafkaService *service.KafkaService
 }
 
 // NewChatHandler creates a new ChatHandler.
-func NewChatHandler(chatUsecase *usecase.ChatUsecase, kafkaService *service.KafkaService) *ChatHandler {
+func NewChatHandler(chatUsecase *usecase.ChatUsecase, tagUsecase *usecase.TagUsecase, kafkaService *service.KafkaService) *ChatHandler {
 	return &ChatHandler{
 		chatUsecase:  chatUsecase,
+		tagUsecase:   tagUsecase,
 		kafkaService: kafkaService,
 	}
 }
@@ -69,6 +74,10 @@ func (h *ChatHandler) StartChat(c *fiber.Ctx) error {
 		})
 	}
 
+	// A customer token from an earlier chat lets the customer continue as the
+	// same chat user
+	req.Customer = middleware.GetCustomerFromContext(c)
+
 	// Get client IP
 	ipAddress := c.IP()
 
@@ -97,6 +106,9 @@ func (h *ChatHandler) StartChat(c *fiber.Ctx) error {
 // @Param request body domain.SendMessageRequest true "Send message request"
 // @Success 200 {object} domain.ApiResponse{data=domain.SendMessageResponse}
 // @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 403 {object} domain.ApiResponse
+// @Security BearerAuth
 // @Router /api/chat/message [post]
 func (h *ChatHandler) SendMessage(c *fiber.Ctx) error {
 	var req domain.SendMessageRequest
@@ -108,6 +120,19 @@ func (h *ChatHandler) SendMessage(c *fiber.Ctx) error {
 		})
 	}
 
+	// Customers write into the session of their token, staff users are agents
+	var senderID *uuid.UUID
+	senderType := "customer"
+
+	if customer := middleware.GetCustomerFromContext(c); customer != nil {
+		if req.SessionID == uuid.Nil {
+			req.SessionID = customer.SessionID
+		}
+		if req.SessionID != customer.SessionID {
+			return middleware.CustomerSessionForbidden(c)
+		}
+	}
+
 	// Validate request
 	if req.SessionID == uuid.Nil || req.Message == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
@@ -117,10 +142,6 @@ func (h *ChatHandler) SendMessage(c *fiber.Ctx) error {
 		})
 	}
 
-	// Determine sender type - if user is in context, it's an agent, otherwise customer
-	var senderID *uuid.UUID
-	senderType := "customer"
-
 	if user := middleware.GetUserFromContext(c); user != nil {
 		userUUID, err := uuid.Parse(user.ID)
 		if err != nil {
@@ -151,56 +172,8 @@ func (h *ChatHandler) SendMessage(c *fiber.Ctx) error {
 	// }
 
 	// Publish message to Kafka
-	if h.kafkaService != nil && message != nil {
-		// Parse IDs for Kafka message
-		messageUUID, _ := uuid.Parse(message.ID)
-		sessionUUID, _ := uuid.Parse(message.SessionID)
-
-		// Create a clean message object without GORM associations for Kafka
-		kafkaMessage := struct {
-			ID          uuid.UUID  `json:"id"`
-			SessionID   uuid.UUID  `json:"session_id"`
-			SenderID    *uuid.UUID `json:"sender_id"`
-			SenderType  string     `json:"sender_type"`
-			Message     string     `json:"message"`
-			MessageType string     `json:"message_type"`
-			Attachments []string   `json:"attachments"`
-			ReadAt      *time.Time `json:"read_at"`
-			CreatedAt   time.Time  `json:"created_at"`
-			UpdatedAt   time.Time  `json:"updated_at"`
-		}{
-			ID:        messageUUID,
-			SessionID: sessionUUID,
-			SenderID: func() *uuid.UUID {
-				if message.SenderID.Valid {
-					if parsed, err := uuid.Parse(message.SenderID.String); err == nil {
-						return &parsed
-					}
-				}
-				return nil
-			}(),
-			SenderType:  message.SenderType,
-			Message:     message.Message,
-			MessageType: message.MessageType,
-			Attachments: message.Attachments,
-			ReadAt: func() *time.Time {
-				if message.ReadAt.Valid {
-					return &message.ReadAt.Time
-				}
-				return nil
-			}(),
-			CreatedAt: message.CreatedAt,
-			UpdatedAt: message.UpdatedAt,
-		}
-
-		log.Printf("Publishing message to Kafka: ID=%s, SessionID=%s, SenderType=%s, Message=%s",
-			kafkaMessage.ID, kafkaMessage.SessionID, kafkaMessage.SenderType, kafkaMessage.Message)
-		if err := h.kafkaService.PublishMessage(c.Context(), kafkaMessage); err != nil {
-			// Log error, tapi tidak broadcast langsung
-			log.Printf("Failed to publish message to Kafka: %v", err)
-		} else {
-			log.Printf("Successfully published message to Kafka")
-		}
+	if message != nil {
+		h.publishMessage(c.Context(), message)
 	}
 
 	return c.JSON(domain.ApiResponse{
@@ -240,9 +213,9 @@ func (h *ChatHandler) AssignAgent(c *fiber.Ctx) error {
 		})
 	}
 
-	err := h.chatUsecase.AssignAgent(c.Context(), &req)
+	err := h.chatUsecase.AssignAgent(c.Context(), &req, middleware.GetDepartmentScopeFromContext(c))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+		return c.Status(sessionErrorStatus(err)).JSON(domain.ApiResponse{
 			Success: false,
 			Message: "Failed to assign agent",
 			Error:   err.Error(),
@@ -285,6 +258,25 @@ func (h *ChatHandler) CloseSession(c *fiber.Ctx) error {
 		})
 	}
 
+	// A rating sent with the close must be the customer's, relayed by staff.
+	// Check it before closing so a bad rating does not leave a closed session.
+	if req.Rating != nil {
+		if !req.RatingRelayed {
+			return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Only a rating relayed from the customer can be saved on close",
+				Error:   "rating_relayed must be true when rating is set",
+			})
+		}
+		if err := usecase.ValidateRating(*req.Rating); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Invalid rating",
+				Error:   err.Error(),
+			})
+		}
+	}
+
 	userID := middleware.GetUserIDFromContext(c)
 	var userUUID *uuid.UUID
 	if userID != nil {
@@ -293,21 +285,99 @@ func (h *ChatHandler) CloseSession(c *fiber.Ctx) error {
 		}
 	}
 
-	err := h.chatUsecase.CloseSession(c.Context(), req.SessionID, req.Reason, userUUID)
+	err := h.chatUsecase.CloseSession(c.Context(), req.SessionID, req.Reason, userUUID, middleware.GetDepartmentScopeFromContext(c))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+		return c.Status(sessionErrorStatus(err)).JSON(domain.ApiResponse{
 			Success: false,
 			Message: "Failed to close session",
 			Error:   err.Error(),
 		})
 	}
 
+	// Store the customer's CSAT rating relayed together with the close, if any
+	if req.Rating != nil {
+		if _, err := h.chatUsecase.RateSession(c.Context(), req.SessionID, &domain.RateSessionRequest{
+			Rating:   *req.Rating,
+			Feedback: req.Feedback,
+		}, userUUID); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Session closed but failed to save rating",
+				Error:   err.Error(),
+			})
+		}
+	}
+
 	return c.JSON(domain.ApiResponse{
 		Success: true,
 		Message: "Session closed successfully",
 	})
 }
 
+// TransferSession godoc
+// @Summary Transfer chat session
+// @Description Transfer a chat session to another agent or department
+// @Tags Chat
+// @Accept json
+// @Produce json
+// @Param request body domain.TransferChatRequest true "Transfer chat request"
+// @Success 200 {object} domain.ApiResponse
+// @Failure 400 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/chat-management/agent/transfer [post]
+func (h *ChatHandler) TransferSession(c *fiber.Ctx) error {
+	var req domain.TransferChatRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	// Validate request
+	if req.SessionID == uuid.Nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Session ID is required",
+			Error:   "validation failed",
+		})
+	}
+
+	if req.NewAgentID == nil && req.NewDepartmentID == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Either new_agent_id or new_department_id is required",
+			Error:   "validation failed",
+		})
+	}
+
+	userID := middleware.GetUserIDFromContext(c)
+	var userUUID *uuid.UUID
+	if userID != nil {
+		if parsed, err := uuid.Parse(*userID); err == nil {
+			userUUID = &parsed
+		}
+	}
+
+	message, err := h.chatUsecase.TransferSession(c.Context(), &req, userUUID, middleware.GetDepartmentScopeFromContext(c))
+	if err != nil {
+		return c.Status(sessionErrorStatus(err)).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to transfer session",
+			Error:   err.Error(),
+		})
+	}
+
+	// Let the customer know about the handoff
+	h.publishMessage(c.Context(), message)
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Session transferred successfully",
+	})
+}
+
 // GetSessionMessages godoc
 // @Summary Get chat session messages
 // @Description Get all messages for a chat session
@@ -352,12 +422,32 @@ func (h *ChatHandler) GetSessionMessages(c *fiber.Ctx) error {
 // @Description Get all chat sessions waiting for agent assignment
 // @Tags Chat
 // @Produce json
+// @Param department_id query string false "Department ID filter"
 // @Success 200 {object} domain.ApiResponse{data=[]models.ChatSessionMinimalResponse}
+// @Failure 403 {object} domain.ApiResponse
 // @Failure 500 {object} domain.ApiResponse
 // @Security BearerAuth
 // @Router /api/chat/waiting [get]
 func (h *ChatHandler) GetWaitingSessions(c *fiber.Ctx) error {
-	sessions, err := h.chatUsecase.GetWaitingSessions(c.Context())
+	var departmentID *uuid.UUID
+	if departmentIDStr := c.Query("department_id"); departmentIDStr != "" {
+		id, err := uuid.Parse(departmentIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Invalid department ID format",
+				Error:   err.Error(),
+			})
+		}
+		departmentID = &id
+	}
+
+	departmentID, ok := scopedDepartment(c, departmentID)
+	if !ok {
+		return departmentForbidden(c)
+	}
+
+	sessions, err := h.chatUsecase.GetWaitingSessions(c.Context(), departmentID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
 			Success: false,
@@ -369,6 +459,11 @@ func (h *ChatHandler) GetWaitingSessions(c *fiber.Ctx) error {
 	// Convert entities to clean response using mapper
 	sessionResponses := mappers.ChatSessionsToMinimalResponse(sessions)
 
+	tags := h.sessionTags(c.Context(), sessions)
+	for i := range sessionResponses {
+		sessionResponses[i].Tags = mappers.ChatTagsToResponse(tags[sessionResponses[i].ID])
+	}
+
 	return c.JSON(domain.ApiResponse{
 		Success: true,
 		Message: "Waiting sessions retrieved successfully",
@@ -381,12 +476,32 @@ func (h *ChatHandler) GetWaitingSessions(c *fiber.Ctx) error {
 // @Description Get all active chat sessions
 // @Tags Chat
 // @Produce json
+// @Param department_id query string false "Department ID filter"
 // @Success 200 {object} domain.ApiResponse{data=[]models.ChatSessionMinimalResponse}
+// @Failure 403 {object} domain.ApiResponse
 // @Failure 500 {object} domain.ApiResponse
 // @Security BearerAuth
 // @Router /api/chat/active [get]
 func (h *ChatHandler) GetActiveSessions(c *fiber.Ctx) error {
-	sessions, err := h.chatUsecase.GetActiveSessions(c.Context())
+	var departmentID *uuid.UUID
+	if departmentIDStr := c.Query("department_id"); departmentIDStr != "" {
+		id, err := uuid.Parse(departmentIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Invalid department ID format",
+				Error:   err.Error(),
+			})
+		}
+		departmentID = &id
+	}
+
+	departmentID, ok := scopedDepartment(c, departmentID)
+	if !ok {
+		return departmentForbidden(c)
+	}
+
+	sessions, err := h.chatUsecase.GetActiveSessions(c.Context(), departmentID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
 			Success: false,
@@ -398,6 +513,11 @@ func (h *ChatHandler) GetActiveSessions(c *fiber.Ctx) error {
 	// Convert entities to clean response using mapper
 	sessionResponses := mappers.ChatSessionsToMinimalResponse(sessions)
 
+	tags := h.sessionTags(c.Context(), sessions)
+	for i := range sessionResponses {
+		sessionResponses[i].Tags = mappers.ChatTagsToResponse(tags[sessionResponses[i].ID])
+	}
+
 	return c.JSON(domain.ApiResponse{
 		Success: true,
 		Message: "Active sessions retrieved successfully",
@@ -454,6 +574,11 @@ func (h *ChatHandler) GetAgentSessions(c *fiber.Ctx) error {
 	// Convert entities to clean response using mapper
 	sessionResponses := mappers.ChatSessionsToMinimalResponse(sessions)
 
+	tags := h.sessionTags(c.Context(), sessions)
+	for i := range sessionResponses {
+		sessionResponses[i].Tags = mappers.ChatTagsToResponse(tags[sessionResponses[i].ID])
+	}
+
 	totalPages := (total + limit - 1) / limit
 	pagination := domain.PaginationInfo{
 		Page:       page,
@@ -472,55 +597,92 @@ func (h *ChatHandler) GetAgentSessions(c *fiber.Ctx) error {
 
 // GetSessions godoc
 // @Summary Get chat sessions
-// @Description Get chat sessions with pagination and filters
+// @Description Search and filter chat sessions with pagination. Search matches the topic, contact name and email, and message bodies.
 // @Tags Chat
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number"
-// @Param limit query int false "Items per page"
+// @Param page_size query int false "Items per page (max 100, limit is also accepted)"
 // @Param status query string false "Session status filter"
 // @Param agent_id query string false "Agent ID filter"
 // @Param department_id query string false "Department ID filter"
-// @Success 200 {object} domain.ApiResponse{data=[]models.ChatSessionMinimalResponse}
+// @Param customer_id query string false "Chat user ID filter"
+// @Param tag_id query string false "Tag ID filter"
+// @Param priority query string false "Priority filter"
+// @Param date_from query string false "Sessions started on or after this date (YYYY-MM-DD)"
+// @Param date_to query string false "Sessions started on or before this date (YYYY-MM-DD)"
+// @Param search query string false "Free-text search"
+// @Param sort_by query string false "created_at, started_at, ended_at, updated_at, status or priority"
+// @Param sort_dir query string false "asc or desc (default desc)"
+// @Success 200 {object} domain.ApiResponse{data=models.PaginatedResponse[models.ChatSessionDetailResponse]}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 403 {object} domain.ApiResponse
 // @Failure 500 {object} domain.ApiResponse
-// @Router /api/chat/sessions [get]
+// @Security BearerAuth
+// @Router /api/chat-management/admin/sessions [get]
 func (h *ChatHandler) GetSessions(c *fiber.Ctx) error {
-	// Parse query parameters
-	page := c.QueryInt("page", 1)
-	limit := c.QueryInt("limit", 20)
-	status := c.Query("status")
-	agentIDStr := c.Query("agent_id")
-	departmentIDStr := c.Query("department_id")
+	filter := &domain.SessionListRequest{
+		Status:   c.Query("status"),
+		Priority: c.Query("priority"),
+		PaginationRequest: domain.PaginationRequest{
+			Page:     c.QueryInt("page", 1),
+			PageSize: c.QueryInt("page_size", c.QueryInt("limit", 20)),
+			Search:   c.Query("search"),
+			SortBy:   c.Query("sort_by"),
+			SortDir:  c.Query("sort_dir"),
+		},
+	}
 
-	var agentID *uuid.UUID
-	if agentIDStr != "" {
-		id, err := uuid.Parse(agentIDStr)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
-				Success: false,
-				Message: "Invalid agent ID format",
-				Error:   err.Error(),
-			})
-		}
-		agentID = &id
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
 	}
 
-	var departmentID *uuid.UUID
-	if departmentIDStr != "" {
-		id, err := uuid.Parse(departmentIDStr)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
-				Success: false,
-				Message: "Invalid department ID format",
-				Error:   err.Error(),
-			})
-		}
-		departmentID = &id
+	if filter.SortDir != "" && filter.SortDir != "asc" && filter.SortDir != "desc" {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid sort direction",
+			Error:   "sort_dir must be asc or desc",
+		})
+	}
+
+	var err error
+	if filter.AgentID, err = optionalUUIDQuery(c, "agent_id"); err != nil {
+		return invalidQueryID(c, "agent", err)
+	}
+	if filter.DepartmentID, err = optionalUUIDQuery(c, "department_id"); err != nil {
+		return invalidQueryID(c, "department", err)
+	}
+	if filter.CustomerID, err = optionalUUIDQuery(c, "customer_id"); err != nil {
+		return invalidQueryID(c, "customer", err)
+	}
+	if filter.TagID, err = optionalUUIDQuery(c, "tag_id"); err != nil {
+		return invalidQueryID(c, "tag", err)
+	}
+
+	if filter.DateFrom, filter.DateTo, err = parseSessionDates(c.Query("date_from"), c.Query("date_to")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid date range",
+			Error:   err.Error(),
+		})
+	}
+
+	departmentID, ok := scopedDepartment(c, filter.DepartmentID)
+	if !ok {
+		return departmentForbidden(c)
 	}
+	filter.DepartmentID = departmentID
 
-	sessions, total, err := h.chatUsecase.GetSessions(c.Context(), page, limit, status, agentID, departmentID)
+	sessions, total, err := h.chatUsecase.GetSessions(c.Context(), filter)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
+		status := fiber.StatusInternalServerError
+		if err.Error() == "unsupported sort field" {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(domain.ApiResponse{
 			Success: false,
 			Message: "Failed to get sessions",
 			Error:   err.Error(),
@@ -530,19 +692,75 @@ func (h *ChatHandler) GetSessions(c *fiber.Ctx) error {
 	// Convert entities to clean response using mapper
 	sessionResponses := mappers.ChatSessionsToDetailResponse(sessions)
 
-	totalPages := (total + limit - 1) / limit
-	pagination := domain.PaginationInfo{
-		Page:       page,
-		Limit:      limit,
-		Total:      total,
-		TotalPages: totalPages,
+	tags := h.sessionTags(c.Context(), sessions)
+	for i := range sessionResponses {
+		sessionResponses[i].Tags = mappers.ChatTagsToResponse(tags[sessionResponses[i].ID])
 	}
 
-	return c.JSON(domain.PaginatedResponse{
-		Success:    true,
-		Message:    "Sessions retrieved successfully",
-		Data:       sessionResponses,
-		Pagination: pagination,
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Sessions retrieved successfully",
+		Data:    mappers.CreatePaginatedResponse(sessionResponses, filter.Page, filter.PageSize, int64(total)),
+	})
+}
+
+// SearchMessages godoc
+// @Summary Search messages
+// @Description Full-text search over customer and agent messages in Indonesian and English, best matches first. Users without the departments:all permission only find messages of their own department.
+// @Tags Chat
+// @Produce json
+// @Param q query string true "Search query, supports quoted phrases, or and -word"
+// @Param department_id query string false "Department ID filter"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Items per page (max 100)"
+// @Success 200 {object} domain.ApiResponse{data=models.PaginatedResponse[domain.MessageSearchResult]}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 403 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/chat-management/search [get]
+func (h *ChatHandler) SearchMessages(c *fiber.Ctx) error {
+	page := c.QueryInt("page", 1)
+	pageSize := c.QueryInt("page_size", 20)
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	departmentID, err := optionalUUIDQuery(c, "department_id")
+	if err != nil {
+		return invalidQueryID(c, "department", err)
+	}
+
+	departmentID, ok := scopedDepartment(c, departmentID)
+	if !ok {
+		return departmentForbidden(c)
+	}
+
+	results, total, err := h.chatUsecase.SearchMessages(c.Context(), &domain.MessageSearchRequest{
+		Query:        c.Query("q"),
+		DepartmentID: departmentID,
+		Limit:        pageSize,
+		Offset:       (page - 1) * pageSize,
+	})
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if err.Error() == "search query is required" {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to search messages",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Messages retrieved successfully",
+		Data:    mappers.CreatePaginatedResponse(results, page, pageSize, int64(total)),
 	})
 }
 
@@ -596,6 +814,12 @@ func (h *ChatHandler) GetSession(c *fiber.Ctx) error {
 	// Convert entity to clean response using mapper
 	response := mappers.ChatSessionToDetailResponse(session)
 
+	// Tags are internal labels, customers do not see them
+	if middleware.GetUserFromContext(c) != nil {
+		tags := h.sessionTags(c.Context(), []*domain.ChatSession{session})
+		response.Tags = mappers.ChatTagsToResponse(tags[session.ID])
+	}
+
 	return c.JSON(domain.ApiResponse{
 		Success: true,
 		Message: "Session retrieved successfully",
@@ -603,6 +827,106 @@ func (h *ChatHandler) GetSession(c *fiber.Ctx) error {
 	})
 }
 
+// GetQueueStatus godoc
+// @Summary Get queue position
+// @Description Get the waiting queue position and estimated wait time of a chat session
+// @Tags Chat
+// @Accept json
+// @Produce json
+// @Param session_id path string true "Session ID"
+// @Success 200 {object} domain.ApiResponse{data=domain.QueueStatusResponse}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Router /api/chat/session/{session_id}/queue [get]
+func (h *ChatHandler) GetQueueStatus(c *fiber.Ctx) error {
+	sessionID, err := uuid.Parse(c.Params("session_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid session ID format",
+			Error:   err.Error(),
+		})
+	}
+
+	status, err := h.chatUsecase.GetQueueStatus(c.Context(), sessionID)
+	if err != nil {
+		if err.Error() == "chat session not found" {
+			return c.Status(fiber.StatusNotFound).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Session not found",
+				Error:   err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to get queue status",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Queue status retrieved successfully",
+		Data:    status,
+	})
+}
+
+// RateSession godoc
+// @Summary Rate chat session
+// @Description Submit a customer satisfaction rating (1-5) and optional feedback for a closed session. Each session can be rated once.
+// @Tags Chat
+// @Accept json
+// @Produce json
+// @Param session_id path string true "Session ID"
+// @Param request body domain.RateSessionRequest true "Rating"
+// @Success 201 {object} domain.ApiResponse{data=domain.SessionRating}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 409 {object} domain.ApiResponse
+// @Router /api/chat/session/{session_id}/rating [post]
+func (h *ChatHandler) RateSession(c *fiber.Ctx) error {
+	sessionID, err := uuid.Parse(c.Params("session_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid session ID format",
+			Error:   err.Error(),
+		})
+	}
+
+	var req domain.RateSessionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	rating, err := h.chatUsecase.RateSession(c.Context(), sessionID, &req, nil)
+	if err != nil {
+		status := fiber.StatusBadRequest
+		switch err.Error() {
+		case "chat session not found":
+			status = fiber.StatusNotFound
+		case "session has already been rated":
+			status = fiber.StatusConflict
+		}
+		return c.Status(status).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to rate session",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Thank you for your feedback",
+		Data:    rating,
+	})
+}
+
 // GetSessionConnectionStatus godoc
 // @Summary Get session connection status
 // @Description Get connection status of clients in a chat session (now handled by WebSocket service)
@@ -647,6 +971,14 @@ func (h *ChatHandler) SetSessionContact(c *fiber.Ctx) error {
 		})
 	}
 
+	customer := middleware.GetCustomerFromContext(c)
+	if req.SessionID == uuid.Nil {
+		req.SessionID = customer.SessionID
+	}
+	if req.SessionID != customer.SessionID {
+		return middleware.CustomerSessionForbidden(c)
+	}
+
 	resp, err := h.chatUsecase.SetSessionContact(c.Context(), &req)
 	if err != nil {
 		if err.Error() == "session not found" {
@@ -681,7 +1013,8 @@ func (h *ChatHandler) LinkOSSUser(c *fiber.Ctx) error {
 		})
 	}
 
-	resp, err := h.chatUsecase.LinkOSSUser(c.Context(), &req)
+	customer := middleware.GetCustomerFromContext(c)
+	resp, err := h.chatUsecase.LinkOSSUser(c.Context(), &req, customer.ChatUserID)
 	if err != nil {
 		if err.Error() == "anonymous user not found" {
 			return c.Status(fiber.StatusNotFound).JSON(domain.ApiResponse{
@@ -711,25 +1044,10 @@ func (h *ChatHandler) LinkOSSUser(c *fiber.Ctx) error {
 	})
 }
 
-// GetChatHistory gets chat history for a user
+// GetChatHistory gets chat history of the chat user of the customer token
 func (h *ChatHandler) GetChatHistory(c *fiber.Ctx) error {
-	var req domain.GetChatHistoryRequest
-
-	// Parse query parameters
-	if browserUUIDStr := c.Query("browser_uuid"); browserUUIDStr != "" {
-		browserUUID, err := uuid.Parse(browserUUIDStr)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
-				Success: false,
-				Message: "Invalid browser_uuid format",
-				Error:   err.Error(),
-			})
-		}
-		req.BrowserUUID = &browserUUID
-	}
-
-	if ossUserID := c.Query("oss_user_id"); ossUserID != "" {
-		req.OSSUserID = &ossUserID
+	req := domain.GetChatHistoryRequest{
+		ChatUserID: middleware.GetCustomerFromContext(c).ChatUserID,
 	}
 
 	// Parse pagination parameters
@@ -761,15 +1079,6 @@ func (h *ChatHandler) GetChatHistory(c *fiber.Ctx) error {
 		req.Offset = 0 // Default offset
 	}
 
-	// Validate that at least one identifier is provided
-	if req.BrowserUUID == nil && req.OSSUserID == nil {
-		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
-			Success: false,
-			Message: "Either browser_uuid or oss_user_id must be provided",
-			Error:   "validation failed",
-		})
-	}
-
 	resp, err := h.chatUsecase.GetChatHistory(c.Context(), &req)
 	if err != nil {
 		if err.Error() == "chat user not found" {
@@ -792,3 +1101,228 @@ func (h *ChatHandler) GetChatHistory(c *fiber.Ctx) error {
 		Data:    resp,
 	})
 }
+
+// RefreshCustomerToken godoc
+// @Summary Renew customer token
+// @Description Issue a new customer token for the chat session of the current one, before it expires. Not possible once the session is closed.
+// @Tags Chat
+// @Produce json
+// @Success 200 {object} domain.ApiResponse{data=domain.CustomerTokenResponse}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/chat/token [post]
+func (h *ChatHandler) RefreshCustomerToken(c *fiber.Ctx) error {
+	token, err := h.chatUsecase.RefreshCustomerToken(c.Context(), middleware.GetCustomerFromContext(c))
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if err.Error() == "chat session is closed" || err.Error() == "chat session not found" {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to renew customer token",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Customer token renewed successfully",
+		Data:    token,
+	})
+}
+
+// RequireSessionAccess rejects staff acting on a session that is not assigned
+// to them, unless their role may override it. The session ID is read from the
+// session_id or id route parameter, or else from the session_id body field.
+// The "assign" action only lets users assign sessions to themselves; assigning
+// other agents goes through the department scoped admin route. Overrides are
+// logged once the handler succeeded.
+func (h *ChatHandler) RequireSessionAccess(action string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := middleware.GetUserFromContext(c)
+		if user == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "User not found in context",
+				Error:   "authentication required",
+			})
+		}
+
+		var body struct {
+			SessionID string `json:"session_id"`
+			AgentID   string `json:"agent_id"`
+		}
+		if c.Method() != fiber.MethodGet {
+			_ = c.BodyParser(&body)
+		}
+
+		rawID := c.Params("session_id")
+		if rawID == "" {
+			rawID = c.Params("id")
+		}
+		if rawID == "" {
+			rawID = body.SessionID
+		}
+
+		sessionID, err := uuid.Parse(rawID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Invalid session ID",
+				Error:   "session_id is required",
+			})
+		}
+
+		if agentID, _ := uuid.Parse(body.AgentID); action == "assign" && agentID.String() != user.ID {
+			return c.Status(fiber.StatusForbidden).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Access to chat session denied",
+				Error:   "you can only assign sessions to yourself",
+			})
+		}
+
+		override, err := h.chatUsecase.AuthorizeSession(c.Context(), sessionID, user, action)
+		if err != nil {
+			status := sessionErrorStatus(err)
+			if status == fiber.StatusBadRequest {
+				status = fiber.StatusInternalServerError
+			}
+			return c.Status(status).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Access to chat session denied",
+				Error:   err.Error(),
+			})
+		}
+
+		if err := c.Next(); err != nil || override == nil {
+			return err
+		}
+
+		if c.Response().StatusCode() < fiber.StatusBadRequest {
+			if err := h.chatUsecase.RecordAccessOverride(c.Context(), override); err != nil {
+				log.Printf("Failed to record access override on session %s: %v", override.SessionID, err)
+			}
+		}
+
+		return nil
+	}
+}
+
+// scopedDepartment applies the department scope of the user to a department
+// filter. Returns false when the user asked for a department outside the scope.
+func scopedDepartment(c *fiber.Ctx, requested *uuid.UUID) (*uuid.UUID, bool) {
+	scope := middleware.GetDepartmentScopeFromContext(c)
+	if scope == nil {
+		return requested, true
+	}
+
+	if requested != nil && *requested != *scope {
+		return nil, false
+	}
+	return scope, true
+}
+
+// optionalUUIDQuery parses an optional ID query parameter
+func optionalUUIDQuery(c *fiber.Ctx, key string) (*uuid.UUID, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func invalidQueryID(c *fiber.Ctx, name string, err error) error {
+	return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+		Success: false,
+		Message: "Invalid " + name + " ID format",
+		Error:   err.Error(),
+	})
+}
+
+// parseSessionDates parses the optional YYYY-MM-DD date_from and date_to of
+// session lists as local days. date_to is inclusive and returned as the start
+// of the next day.
+func parseSessionDates(dateFrom, dateTo string) (*time.Time, *time.Time, error) {
+	var from, to *time.Time
+	if dateFrom != "" {
+		parsed, err := utils.ParseDate(dateFrom)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid date_from: %w", err)
+		}
+		from = &parsed
+	}
+
+	if dateTo != "" {
+		parsed, err := utils.ParseDate(dateTo)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid date_to: %w", err)
+		}
+		parsed = parsed.AddDate(0, 0, 1)
+		to = &parsed
+	}
+
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, nil, fmt.Errorf("date_from must not be after date_to")
+	}
+
+	return from, to, nil
+}
+
+func departmentForbidden(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(domain.ApiResponse{
+		Success: false,
+		Message: "Insufficient permissions",
+		Error:   "department is outside your department scope",
+	})
+}
+
+// sessionErrorStatus maps errors of session actions to HTTP status codes
+func sessionErrorStatus(err error) int {
+	switch err.Error() {
+	case "session is outside your department", "agent is outside your department",
+		"cannot transfer outside your department", "you are not assigned to this session":
+		return fiber.StatusForbidden
+	case "chat session not found":
+		return fiber.StatusNotFound
+	}
+	return fiber.StatusBadRequest
+}
+
+// sessionTags loads the tags of sessions by session ID. Tags only decorate
+// the response, so failing to load them is logged rather than returned.
+func (h *ChatHandler) sessionTags(ctx context.Context, sessions []*domain.ChatSession) map[string][]*domain.ChatTag {
+	sessionIDs := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		sessionIDs = append(sessionIDs, session.ID)
+	}
+
+	tags, err := h.tagUsecase.GetSessionTags(ctx, sessionIDs)
+	if err != nil {
+		log.Printf("Failed to load session tags: %v", err)
+		return nil
+	}
+	return tags
+}
+
+// publishMessage publishes a chat message to Kafka so the WebSocket service can broadcast it
+func (h *ChatHandler) publishMessage(ctx context.Context, message *domain.ChatMessage) {
+	if h.kafkaService == nil || message == nil {
+		return
+	}
+
+	log.Printf("Publishing message to Kafka: ID=%s, SessionID=%s, SenderType=%s, Message=%s",
+		message.ID, message.SessionID, message.SenderType, message.Message)
+	if err := h.kafkaService.PublishChatMessage(ctx, message); err != nil {
+		// Log error, tapi tidak broadcast langsung
+		log.Printf("Failed to publish message to Kafka: %v", err)
+	} else {
+		log.Printf("Successfully published message to Kafka")
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSessionDates(t *testing.T) {
+	day := func(year int, month time.Month, d int) *time.Time {
+		value := time.Date(year, month, d, 0, 0, 0, 0, time.Local)
+		return &value
+	}
+
+	tests := []struct {
+		name     string
+		dateFrom string
+		dateTo   string
+		wantFrom *time.Time
+		wantTo   *time.Time
+		wantErr  bool
+	}{
+		{name: "no dates"},
+		{name: "only from", dateFrom: "2025-03-10", wantFrom: day(2025, time.March, 10)},
+		{name: "to is exclusive next day", dateTo: "2025-03-10", wantTo: day(2025, time.March, 11)},
+		{name: "to at month end", dateTo: "2025-02-28", wantTo: day(2025, time.March, 1)},
+		{name: "same day", dateFrom: "2025-03-10", dateTo: "2025-03-10", wantFrom: day(2025, time.March, 10), wantTo: day(2025, time.March, 11)},
+		{name: "range", dateFrom: "2025-03-01", dateTo: "2025-03-31", wantFrom: day(2025, time.March, 1), wantTo: day(2025, time.April, 1)},
+		{name: "from after to", dateFrom: "2025-03-11", dateTo: "2025-03-10", wantErr: true},
+		{name: "invalid from", dateFrom: "10-03-2025", wantErr: true},
+		{name: "invalid to", dateTo: "2025-13-01", wantErr: true},
+		{name: "timestamp is not a date", dateFrom: "2025-03-10T00:00:00Z", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		from, to, err := parseSessionDates(tt.dateFrom, tt.dateTo)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+
+		if !sameTime(from, tt.wantFrom) {
+			t.Errorf("%s: from = %v, want %v", tt.name, from, tt.wantFrom)
+		}
+		if !sameTime(to, tt.wantTo) {
+			t.Errorf("%s: to = %v, want %v", tt.name, to, tt.wantTo)
+		}
+	}
+}
+
+func sameTime(got, want *time.Time) bool {
+	if got == nil || want == nil {
+		return got == want
+	}
+	return got.Equal(*want)
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"github.com/novianakbar/livechat-be/internal/mappers"
+	"github.com/novianakbar/livechat-be/internal/usecase"
+)
+
+type DepartmentHandler struct {
+	departmentUsecase *usecase.DepartmentUsecase
+}
+
+func NewDepartmentHandler(departmentUsecase *usecase.DepartmentUsecase) *DepartmentHandler {
+	return &DepartmentHandler{
+		departmentUsecase: departmentUsecase,
+	}
+}
+
+// GetDepartments godoc
+// @Summary Get departments
+// @Description List all departments with their number of agents (admin only)
+// @Tags Departments
+// @Produce json
+// @Success 200 {object} domain.ApiResponse{data=[]models.DepartmentDetailResponse}
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/departments [get]
+func (h *DepartmentHandler) GetDepartments(c *fiber.Ctx) error {
+	departments, agentCounts, err := h.departmentUsecase.GetDepartments(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to get departments",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Departments retrieved successfully",
+		Data:    mappers.DepartmentsToDetailResponse(departments, agentCounts),
+	})
+}
+
+// GetDepartment godoc
+// @Summary Get department
+// @Description Get a department with its number of agents (admin only)
+// @Tags Departments
+// @Produce json
+// @Param id path string true "Department ID"
+// @Success 200 {object} domain.ApiResponse{data=models.DepartmentDetailResponse}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/departments/{id} [get]
+func (h *DepartmentHandler) GetDepartment(c *fiber.Ctx) error {
+	departmentID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidDepartmentID(c, err)
+	}
+
+	department, agentCount, err := h.departmentUsecase.GetDepartment(c.Context(), departmentID)
+	if err != nil {
+		return departmentError(c, "Failed to get department", err)
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Department retrieved successfully",
+		Data:    mappers.DepartmentToDetailResponse(department, agentCount),
+	})
+}
+
+// CreateDepartment godoc
+// @Summary Create department
+// @Description Create a new active department (admin only)
+// @Tags Departments
+// @Accept json
+// @Produce json
+// @Param request body domain.CreateDepartmentRequest true "Department"
+// @Success 201 {object} domain.ApiResponse{data=models.DepartmentDetailResponse}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 409 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/departments [post]
+func (h *DepartmentHandler) CreateDepartment(c *fiber.Ctx) error {
+	var req domain.CreateDepartmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	department, err := h.departmentUsecase.CreateDepartment(c.Context(), &req)
+	if err != nil {
+		return departmentError(c, "Failed to create department", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Department created successfully",
+		Data:    mappers.DepartmentToDetailResponse(department, 0),
+	})
+}
+
+// UpdateDepartment godoc
+// @Summary Update department
+// @Description Update name, description and active state of a department. Inactive departments take no new chats. (admin only)
+// @Tags Departments
+// @Accept json
+// @Produce json
+// @Param id path string true "Department ID"
+// @Param request body domain.UpdateDepartmentRequest true "Department"
+// @Success 200 {object} domain.ApiResponse{data=models.DepartmentDetailResponse}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 409 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/departments/{id} [put]
+func (h *DepartmentHandler) UpdateDepartment(c *fiber.Ctx) error {
+	departmentID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidDepartmentID(c, err)
+	}
+
+	var req domain.UpdateDepartmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	department, agentCount, err := h.departmentUsecase.UpdateDepartment(c.Context(), departmentID, &req)
+	if err != nil {
+		return departmentError(c, "Failed to update department", err)
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Department updated successfully",
+		Data:    mappers.DepartmentToDetailResponse(department, agentCount),
+	})
+}
+
+// DeleteDepartment godoc
+// @Summary Delete department
+// @Description Soft-delete a department. Departments that still have agents or open sessions cannot be deleted. (admin only)
+// @Tags Departments
+// @Produce json
+// @Param id path string true "Department ID"
+// @Success 200 {object} domain.ApiResponse
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 409 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/departments/{id} [delete]
+func (h *DepartmentHandler) DeleteDepartment(c *fiber.Ctx) error {
+	departmentID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidDepartmentID(c, err)
+	}
+
+	if err := h.departmentUsecase.DeleteDepartment(c.Context(), departmentID); err != nil {
+		return departmentError(c, "Failed to delete department", err)
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Department deleted successfully",
+	})
+}
+
+func invalidDepartmentID(c *fiber.Ctx, err error) error {
+	return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+		Success: false,
+		Message: "Invalid department ID",
+		Error:   err.Error(),
+	})
+}
+
+// departmentError maps department usecase errors to HTTP status codes
+func departmentError(c *fiber.Ctx, message string, err error) error {
+	status := fiber.StatusInternalServerError
+	switch err.Error() {
+	case "department name is required":
+		status = fiber.StatusBadRequest
+	case "department not found":
+		status = fiber.StatusNotFound
+	case "department name already exists", "department still has agents", "department still has open sessions":
+		status = fiber.StatusConflict
+	}
+
+	return c.Status(status).JSON(domain.ApiResponse{
+		Success: false,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
@@ -49,23 +49,6 @@ func (h *EmailHandler) SendWelcomeEmail(c *fiber.Ctx) error {
 	return c.JSON(resp)
 }
 
-// SendPasswordResetEmail handles sending a password reset email
-func (h *EmailHandler) SendPasswordResetEmail(c *fiber.Ctx) error {
-	type resetReq struct {
-		To         string `json:"to"`
-		ResetToken string `json:"reset_token"`
-	}
-	var req resetReq
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"success": false, "message": "Invalid request", "error": err.Error()})
-	}
-	resp, err := h.EmailUseCase.SendPasswordResetEmail(context.Background(), req.To, req.ResetToken)
-	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(resp)
-	}
-	return c.JSON(resp)
-}
-
 // SendChatTranscriptEmail handles sending a chat transcript email
 func (h *EmailHandler) SendChatTranscriptEmail(c *fiber.Ctx) error {
 	type transcriptReq struct {
@@ -82,7 +82,13 @@ func (h *ExampleChatHandler) GetSessionsExample(c *fiber.Ctx) error {
 	}
 
 	// Get entities from usecase (need to provide required parameters)
-	sessions, total, err := h.chatUsecase.GetSessions(c.Context(), page, limit, status, nil, nil)
+	sessions, total, err := h.chatUsecase.GetSessions(c.Context(), &domain.SessionListRequest{
+		Status: status,
+		PaginationRequest: domain.PaginationRequest{
+			Page:     page,
+			PageSize: limit,
+		},
+	})
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
 			Success: false,
@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/delivery/middleware"
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"github.com/novianakbar/livechat-be/internal/usecase"
+)
+
+type MFAHandler struct {
+	mfaUsecase *usecase.MFAUsecase
+}
+
+func NewMFAHandler(mfaUsecase *usecase.MFAUsecase) *MFAHandler {
+	return &MFAHandler{
+		mfaUsecase: mfaUsecase,
+	}
+}
+
+// GetStatus godoc
+// @Summary Get my MFA status
+// @Description Whether MFA is enabled, required for the user's role, and how many recovery codes are left
+// @Tags MFA
+// @Produce json
+// @Success 200 {object} domain.ApiResponse{data=domain.MFAStatusResponse}
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/auth/mfa [get]
+func (h *MFAHandler) GetStatus(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return mfaUnauthorized(c)
+	}
+
+	status, err := h.mfaUsecase.GetStatus(c.Context(), user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to get MFA status",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "MFA status retrieved successfully",
+		Data:    status,
+	})
+}
+
+// Enroll godoc
+// @Summary Start MFA enrollment
+// @Description Generate a TOTP secret and its otpauth:// provisioning URI for a QR code. MFA is enabled after the first code is verified.
+// @Tags MFA
+// @Produce json
+// @Success 200 {object} domain.ApiResponse{data=domain.MFAEnrollResponse}
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 409 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/auth/mfa/enroll [post]
+func (h *MFAHandler) Enroll(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return mfaUnauthorized(c)
+	}
+
+	response, err := h.mfaUsecase.Enroll(c.Context(), user)
+	if err != nil {
+		return mfaError(c, "Failed to start MFA enrollment", err)
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Scan the QR code and verify a code to enable MFA",
+		Data:    response,
+	})
+}
+
+// ConfirmEnrollment godoc
+// @Summary Confirm MFA enrollment
+// @Description Verify the first TOTP code to enable MFA. Returns the recovery codes, which are shown only once.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param request body domain.MFACodeRequest true "TOTP code"
+// @Success 200 {object} domain.ApiResponse{data=domain.MFARecoveryCodesResponse}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 409 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/auth/mfa/verify [post]
+func (h *MFAHandler) ConfirmEnrollment(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return mfaUnauthorized(c)
+	}
+
+	var req domain.MFACodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Code is required",
+			Error:   "validation failed",
+		})
+	}
+
+	codes, err := h.mfaUsecase.ConfirmEnrollment(c.Context(), user, req.Code)
+	if err != nil {
+		return mfaError(c, "Failed to enable MFA", err)
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "MFA enabled successfully, store the recovery codes in a safe place",
+		Data:    domain.MFARecoveryCodesResponse{RecoveryCodes: codes},
+	})
+}
+
+// Disable godoc
+// @Summary Disable MFA
+// @Description Turn MFA off with the password and a TOTP or recovery code. Not allowed when MFA is required for the user's role.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param request body domain.MFADisableRequest true "Password and code"
+// @Success 200 {object} domain.ApiResponse
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 403 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/auth/mfa/disable [post]
+func (h *MFAHandler) Disable(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return mfaUnauthorized(c)
+	}
+
+	var req domain.MFADisableRequest
+	if err := c.BodyParser(&req); err != nil || req.Password == "" || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Password and code are required",
+			Error:   "validation failed",
+		})
+	}
+
+	if err := h.mfaUsecase.Disable(c.Context(), user, &req); err != nil {
+		return mfaError(c, "Failed to disable MFA", err)
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "MFA disabled successfully",
+	})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes after verifying a TOTP or recovery code
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param request body domain.MFACodeRequest true "TOTP or recovery code"
+// @Success 200 {object} domain.ApiResponse{data=domain.MFARecoveryCodesResponse}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/auth/mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return mfaUnauthorized(c)
+	}
+
+	var req domain.MFACodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Code is required",
+			Error:   "validation failed",
+		})
+	}
+
+	codes, err := h.mfaUsecase.RegenerateRecoveryCodes(c.Context(), user, req.Code)
+	if err != nil {
+		return mfaError(c, "Failed to regenerate recovery codes", err)
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Recovery codes regenerated successfully",
+		Data:    domain.MFARecoveryCodesResponse{RecoveryCodes: codes},
+	})
+}
+
+// ResetUserMFA godoc
+// @Summary Reset MFA of a user
+// @Description Remove MFA from a user who lost their device, so they can enroll again (admin only)
+// @Tags MFA
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} domain.ApiResponse
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/auth/users/{id}/mfa [delete]
+func (h *MFAHandler) ResetUserMFA(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid user ID",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := h.mfaUsecase.ResetForUser(c.Context(), userID.String()); err != nil {
+		return mfaError(c, "Failed to reset MFA", err)
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "MFA reset successfully",
+	})
+}
+
+// GetPolicies godoc
+// @Summary Get MFA policies
+// @Description List per role whether MFA is required (admin only)
+// @Tags MFA
+// @Produce json
+// @Success 200 {object} domain.ApiResponse{data=[]domain.MFAPolicy}
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/auth/mfa/policy [get]
+func (h *MFAHandler) GetPolicies(c *fiber.Ctx) error {
+	policies, err := h.mfaUsecase.GetPolicies(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to get MFA policies",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "MFA policies retrieved successfully",
+		Data:    policies,
+	})
+}
+
+// SetPolicy godoc
+// @Summary Set MFA policy
+// @Description Require or stop requiring MFA for a role (admin only)
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param request body domain.SetMFAPolicyRequest true "MFA policy"
+// @Success 200 {object} domain.ApiResponse
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/auth/mfa/policy [put]
+func (h *MFAHandler) SetPolicy(c *fiber.Ctx) error {
+	var req domain.SetMFAPolicyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := h.mfaUsecase.SetPolicy(c.Context(), &req); err != nil {
+		return mfaError(c, "Failed to set MFA policy", err)
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "MFA policy updated successfully",
+	})
+}
+
+func mfaUnauthorized(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusUnauthorized).JSON(domain.ApiResponse{
+		Success: false,
+		Message: "User not found in context",
+		Error:   "authentication required",
+	})
+}
+
+// mfaError maps MFA usecase errors to HTTP status codes
+func mfaError(c *fiber.Ctx, message string, err error) error {
+	status := fiber.StatusInternalServerError
+	switch err.Error() {
+	case "invalid verification code", "invalid password", "mfa is not enabled",
+		"mfa enrollment has not been started", "invalid role":
+		status = fiber.StatusBadRequest
+	case "mfa is already enabled":
+		status = fiber.StatusConflict
+	case "mfa is required for your role":
+		status = fiber.StatusForbidden
+	case "user not found":
+		status = fiber.StatusNotFound
+	}
+
+	return c.Status(status).JSON(domain.ApiResponse{
+		Success: false,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
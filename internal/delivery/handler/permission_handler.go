@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"github.com/novianakbar/livechat-be/internal/usecase"
+)
+
+type PermissionHandler struct {
+	permissionUsecase *usecase.PermissionUsecase
+}
+
+func NewPermissionHandler(permissionUsecase *usecase.PermissionUsecase) *PermissionHandler {
+	return &PermissionHandler{
+		permissionUsecase: permissionUsecase,
+	}
+}
+
+// GetPermissions godoc
+// @Summary Get permissions
+// @Description List all permissions and the permissions granted to each role (admin only)
+// @Tags Permissions
+// @Produce json
+// @Success 200 {object} domain.ApiResponse{data=domain.PermissionsResponse}
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/permissions [get]
+func (h *PermissionHandler) GetPermissions(c *fiber.Ctx) error {
+	permissions, err := h.permissionUsecase.GetPermissions(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to get permissions",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Permissions retrieved successfully",
+		Data:    permissions,
+	})
+}
+
+// SetRolePermissions godoc
+// @Summary Set role permissions
+// @Description Replace the permissions of a role. Admin permissions cannot be changed (admin only)
+// @Tags Permissions
+// @Accept json
+// @Produce json
+// @Param role path string true "Role"
+// @Param request body domain.SetRolePermissionsRequest true "Permissions of the role"
+// @Success 200 {object} domain.ApiResponse
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/permissions/roles/{role} [put]
+func (h *PermissionHandler) SetRolePermissions(c *fiber.Ctx) error {
+	var req domain.SetRolePermissionsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := h.permissionUsecase.SetRolePermissions(c.Context(), c.Params("role"), &req); err != nil {
+		status := fiber.StatusInternalServerError
+		if err.Error() == "invalid role" || err.Error() == "admin permissions cannot be changed" ||
+			strings.HasPrefix(err.Error(), "unknown permission") {
+			status = fiber.StatusBadRequest
+		}
+
+		return c.Status(status).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to set role permissions",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Role permissions updated successfully",
+	})
+}
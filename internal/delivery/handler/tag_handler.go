@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"github.com/novianakbar/livechat-be/internal/mappers"
+	"github.com/novianakbar/livechat-be/internal/usecase"
+)
+
+type TagHandler struct {
+	tagUsecase *usecase.TagUsecase
+}
+
+func NewTagHandler(tagUsecase *usecase.TagUsecase) *TagHandler {
+	return &TagHandler{
+		tagUsecase: tagUsecase,
+	}
+}
+
+// GetTags godoc
+// @Summary Get tags
+// @Description List the tag catalogue agents can put on sessions
+// @Tags Tags
+// @Produce json
+// @Success 200 {object} domain.ApiResponse{data=[]models.ChatTagResponse}
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/tags [get]
+func (h *TagHandler) GetTags(c *fiber.Ctx) error {
+	tags, err := h.tagUsecase.GetTags(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Failed to get tags",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Tags retrieved successfully",
+		Data:    mappers.ChatTagsToResponse(tags),
+	})
+}
+
+// CreateTag godoc
+// @Summary Create tag
+// @Description Add a tag to the catalogue. The color defaults to #007bff. (admin only)
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Param request body domain.CreateTagRequest true "Tag"
+// @Success 201 {object} domain.ApiResponse{data=models.ChatTagResponse}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 409 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/tags [post]
+func (h *TagHandler) CreateTag(c *fiber.Ctx) error {
+	var req domain.CreateTagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	tag, err := h.tagUsecase.CreateTag(c.Context(), &req)
+	if err != nil {
+		return tagError(c, "Failed to create tag", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Tag created successfully",
+		Data:    mappers.ChatTagToResponse(tag),
+	})
+}
+
+// UpdateTag godoc
+// @Summary Update tag
+// @Description Rename or recolor a tag. Sessions carrying it show the new values. (admin only)
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Param id path string true "Tag ID"
+// @Param request body domain.UpdateTagRequest true "Tag"
+// @Success 200 {object} domain.ApiResponse{data=models.ChatTagResponse}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 409 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/tags/{id} [put]
+func (h *TagHandler) UpdateTag(c *fiber.Ctx) error {
+	tagID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidTagID(c, err)
+	}
+
+	var req domain.UpdateTagRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	tag, err := h.tagUsecase.UpdateTag(c.Context(), tagID, &req)
+	if err != nil {
+		return tagError(c, "Failed to update tag", err)
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Tag updated successfully",
+		Data:    mappers.ChatTagToResponse(tag),
+	})
+}
+
+// DeleteTag godoc
+// @Summary Delete tag
+// @Description Soft-delete a tag and remove it from all sessions (admin only)
+// @Tags Tags
+// @Produce json
+// @Param id path string true "Tag ID"
+// @Success 200 {object} domain.ApiResponse
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/tags/{id} [delete]
+func (h *TagHandler) DeleteTag(c *fiber.Ctx) error {
+	tagID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidTagID(c, err)
+	}
+
+	if err := h.tagUsecase.DeleteTag(c.Context(), tagID); err != nil {
+		return tagError(c, "Failed to delete tag", err)
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Tag deleted successfully",
+	})
+}
+
+// AddTagToSession godoc
+// @Summary Tag a session
+// @Description Put a tag on a chat session and return the tags of the session
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Param request body domain.AddTagToSessionRequest true "Session and tag"
+// @Success 200 {object} domain.ApiResponse{data=[]models.ChatTagResponse}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 403 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/chat-management/agent/tags [post]
+func (h *TagHandler) AddTagToSession(c *fiber.Ctx) error {
+	var req domain.AddTagToSessionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	tags, err := h.tagUsecase.AddTagToSession(c.Context(), req.SessionID, req.TagID)
+	if err != nil {
+		return tagError(c, "Failed to tag session", err)
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Tag added to session successfully",
+		Data:    mappers.ChatTagsToResponse(tags),
+	})
+}
+
+// RemoveTagFromSession godoc
+// @Summary Untag a session
+// @Description Remove a tag from a chat session and return the tags left
+// @Tags Tags
+// @Produce json
+// @Param session_id path string true "Session ID"
+// @Param tag_id path string true "Tag ID"
+// @Success 200 {object} domain.ApiResponse{data=[]models.ChatTagResponse}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 403 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/chat-management/agent/sessions/{session_id}/tags/{tag_id} [delete]
+func (h *TagHandler) RemoveTagFromSession(c *fiber.Ctx) error {
+	sessionID, err := uuid.Parse(c.Params("session_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid session ID format",
+			Error:   err.Error(),
+		})
+	}
+
+	tagID, err := uuid.Parse(c.Params("tag_id"))
+	if err != nil {
+		return invalidTagID(c, err)
+	}
+
+	tags, err := h.tagUsecase.RemoveTagFromSession(c.Context(), sessionID, tagID)
+	if err != nil {
+		return tagError(c, "Failed to untag session", err)
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "Tag removed from session successfully",
+		Data:    mappers.ChatTagsToResponse(tags),
+	})
+}
+
+func invalidTagID(c *fiber.Ctx, err error) error {
+	return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+		Success: false,
+		Message: "Invalid tag ID",
+		Error:   err.Error(),
+	})
+}
+
+// tagError maps tag usecase errors to HTTP status codes
+func tagError(c *fiber.Ctx, message string, err error) error {
+	status := fiber.StatusInternalServerError
+	switch err.Error() {
+	case "tag name is required", "tag color must be a hex color like #007bff":
+		status = fiber.StatusBadRequest
+	case "tag not found", "chat session not found", "session does not have this tag":
+		status = fiber.StatusNotFound
+	case "tag name already exists":
+		status = fiber.StatusConflict
+	}
+
+	return c.Status(status).JSON(domain.ApiResponse{
+		Success: false,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
@@ -3,6 +3,7 @@ package handler
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/delivery/middleware"
 	"github.com/novianakbar/livechat-be/internal/domain"
 	"github.com/novianakbar/livechat-be/internal/mappers"
 	"github.com/novianakbar/livechat-be/internal/usecase"
@@ -167,3 +168,149 @@ func (h *UserHandler) GetUser(c *fiber.Ctx) error {
 		Data:    userResponse,
 	})
 }
+
+// UpdateUser godoc
+// @Summary Update user
+// @Description Change email, name, role and department of a staff user. Supervisors need a department. (admin only)
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body domain.UpdateUserRequest true "User"
+// @Success 200 {object} domain.ApiResponse{data=models.UserResponse}
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 409 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/users/{id} [put]
+func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidUserID(c, err)
+	}
+
+	var req domain.UpdateUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	user, err := h.userUsecase.UpdateUser(c.Context(), *middleware.GetUserIDFromContext(c), userID, &req)
+	if err != nil {
+		return userError(c, "Failed to update user", err)
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "User updated successfully",
+		Data:    mappers.UserToResponse(user),
+	})
+}
+
+// SetUserActive godoc
+// @Summary Activate or deactivate user
+// @Description Deactivated users are signed out everywhere and their open sessions go back to the queue for other agents (admin only)
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body domain.SetUserActiveRequest true "Active state"
+// @Success 200 {object} domain.ApiResponse
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/users/{id}/active [put]
+func (h *UserHandler) SetUserActive(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidUserID(c, err)
+	}
+
+	var req domain.SetUserActiveRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+			Success: false,
+			Message: "Invalid request body",
+			Error:   err.Error(),
+		})
+	}
+
+	if err := h.userUsecase.SetActive(c.Context(), *middleware.GetUserIDFromContext(c), userID, req.IsActive); err != nil {
+		return userError(c, "Failed to change user status", err)
+	}
+
+	message := "User deactivated successfully"
+	if req.IsActive {
+		message = "User activated successfully"
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: message,
+	})
+}
+
+// DeleteUser godoc
+// @Summary Delete user
+// @Description Soft-delete a staff user. The user is signed out everywhere and their open sessions go back to the queue for other agents. (admin only)
+// @Tags Users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} domain.ApiResponse
+// @Failure 400 {object} domain.ApiResponse
+// @Failure 401 {object} domain.ApiResponse
+// @Failure 404 {object} domain.ApiResponse
+// @Failure 500 {object} domain.ApiResponse
+// @Security BearerAuth
+// @Router /api/users/{id} [delete]
+func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return invalidUserID(c, err)
+	}
+
+	if err := h.userUsecase.DeleteUser(c.Context(), *middleware.GetUserIDFromContext(c), userID); err != nil {
+		return userError(c, "Failed to delete user", err)
+	}
+
+	return c.JSON(domain.ApiResponse{
+		Success: true,
+		Message: "User deleted successfully",
+	})
+}
+
+func invalidUserID(c *fiber.Ctx, err error) error {
+	return c.Status(fiber.StatusBadRequest).JSON(domain.ApiResponse{
+		Success: false,
+		Message: "Invalid user ID format",
+		Error:   err.Error(),
+	})
+}
+
+// userError maps user management errors to HTTP status codes
+func userError(c *fiber.Ctx, message string, err error) error {
+	status := fiber.StatusInternalServerError
+	switch err.Error() {
+	case "invalid role", "email and name are required", "department not found",
+		"supervisor must belong to a department", "you cannot change your own role",
+		"you cannot deactivate your own account", "you cannot delete your own account":
+		status = fiber.StatusBadRequest
+	case "user not found":
+		status = fiber.StatusNotFound
+	case "user with this email already exists":
+		status = fiber.StatusConflict
+	}
+
+	return c.Status(status).JSON(domain.ApiResponse{
+		Success: false,
+		Message: message,
+		Error:   err.Error(),
+	})
+}
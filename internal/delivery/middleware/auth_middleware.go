@@ -1,20 +1,24 @@
 package middleware
 
 import (
+	"log"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/novianakbar/livechat-be/internal/domain"
 	"github.com/novianakbar/livechat-be/internal/usecase"
 )
 
 type AuthMiddleware struct {
-	authUsecase *usecase.AuthUsecase
+	authUsecase       *usecase.AuthUsecase
+	permissionUsecase *usecase.PermissionUsecase
 }
 
-func NewAuthMiddleware(authUsecase *usecase.AuthUsecase) *AuthMiddleware {
+func NewAuthMiddleware(authUsecase *usecase.AuthUsecase, permissionUsecase *usecase.PermissionUsecase) *AuthMiddleware {
 	return &AuthMiddleware{
-		authUsecase: authUsecase,
+		authUsecase:       authUsecase,
+		permissionUsecase: permissionUsecase,
 	}
 }
 
@@ -39,7 +43,7 @@ func (m *AuthMiddleware) RequireAuth() fiber.Handler {
 		}
 
 		token := tokenParts[1]
-		user, err := m.authUsecase.ValidateToken(c.Context(), token)
+		user, sessionID, err := m.authUsecase.ValidateToken(c.Context(), token)
 		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(domain.ApiResponse{
 				Success: false,
@@ -48,8 +52,34 @@ func (m *AuthMiddleware) RequireAuth() fiber.Handler {
 			})
 		}
 
-		// Store user in context
+		// Last-seen is informational, a failed update must not block the request
+		if err := m.authUsecase.TouchSession(c.Context(), sessionID); err != nil {
+			log.Printf("Failed to update last seen of session %s: %v", sessionID, err)
+		}
+
+		// Users who must enroll in MFA can only reach the auth routes until they do
+		if !strings.HasPrefix(c.Path(), "/api/auth/") {
+			pending, err := m.authUsecase.MFAEnrollmentPending(c.Context(), user)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
+					Success: false,
+					Message: "Failed to check MFA status",
+					Error:   err.Error(),
+				})
+			}
+
+			if pending {
+				return c.Status(fiber.StatusForbidden).JSON(domain.ApiResponse{
+					Success: false,
+					Message: "MFA enrollment required",
+					Error:   "your role requires two-factor authentication, enroll via /api/auth/mfa/enroll",
+				})
+			}
+		}
+
+		// Store user and login session in context
 		c.Locals("user", user)
+		c.Locals("session_id", sessionID)
 		return c.Next()
 	}
 }
@@ -79,8 +109,64 @@ func (m *AuthMiddleware) RequireRole(roles ...string) fiber.Handler {
 	}
 }
 
+// RequirePermission lets the request through when the user's role has the
+// permission. Users without the "departments:all" permission are limited to
+// their own department, see GetDepartmentScopeFromContext.
+func (m *AuthMiddleware) RequirePermission(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := c.Locals("user").(*domain.User)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Authentication required",
+				Error:   "user not found in context",
+			})
+		}
+
+		allowed, err := m.permissionUsecase.HasPermission(c.Context(), user.Role, permission)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Failed to check permissions",
+				Error:   err.Error(),
+			})
+		}
+
+		if !allowed {
+			return c.Status(fiber.StatusForbidden).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Insufficient permissions",
+				Error:   "missing permission " + permission,
+			})
+		}
+
+		allDepartments, err := m.permissionUsecase.HasPermission(c.Context(), user.Role, "departments:all")
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Failed to check permissions",
+				Error:   err.Error(),
+			})
+		}
+
+		if !allDepartments {
+			departmentID, err := uuid.Parse(user.DepartmentID.String)
+			if !user.DepartmentID.Valid || err != nil {
+				return c.Status(fiber.StatusForbidden).JSON(domain.ApiResponse{
+					Success: false,
+					Message: "Insufficient permissions",
+					Error:   "user is not assigned to a department",
+				})
+			}
+			c.Locals("department_scope", &departmentID)
+		}
+
+		return c.Next()
+	}
+}
+
 func (m *AuthMiddleware) RequireAgent() fiber.Handler {
-	return m.RequireRole("agent", "admin")
+	return m.RequireRole("agent", "supervisor", "admin")
 }
 
 func (m *AuthMiddleware) RequireAdmin() fiber.Handler {
@@ -102,3 +188,17 @@ func GetUserIDFromContext(c *fiber.Ctx) *string {
 	}
 	return &user.ID
 }
+
+// GetSessionIDFromContext returns the login session of the current request,
+// empty for tokens issued before sessions were tracked
+func GetSessionIDFromContext(c *fiber.Ctx) string {
+	sessionID, _ := c.Locals("session_id").(string)
+	return sessionID
+}
+
+// GetDepartmentScopeFromContext returns the only department the user may act
+// on, or nil when the user is not limited to one
+func GetDepartmentScopeFromContext(c *fiber.Ctx) *uuid.UUID {
+	departmentID, _ := c.Locals("department_scope").(*uuid.UUID)
+	return departmentID
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"github.com/novianakbar/livechat-be/internal/usecase"
+)
+
+// CustomerMiddleware authenticates the chat widget on the public chat routes
+type CustomerMiddleware struct {
+	chatUsecase *usecase.ChatUsecase
+}
+
+func NewCustomerMiddleware(chatUsecase *usecase.ChatUsecase) *CustomerMiddleware {
+	return &CustomerMiddleware{
+		chatUsecase: chatUsecase,
+	}
+}
+
+// RequireCustomer checks the customer token returned by /api/chat/start. On
+// routes with a session_id parameter the token must belong to that session.
+func (m *CustomerMiddleware) RequireCustomer() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		authHeader := c.Get("Authorization")
+		if authHeader == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Authorization header required",
+				Error:   "missing customer token",
+			})
+		}
+
+		tokenParts := strings.Split(authHeader, " ")
+		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+			return c.Status(fiber.StatusUnauthorized).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Invalid authorization header format",
+				Error:   "authorization header must be 'Bearer <token>'",
+			})
+		}
+
+		customer, err := m.chatUsecase.ValidateCustomerToken(c.Context(), tokenParts[1])
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(domain.ApiResponse{
+				Success: false,
+				Message: "Invalid or expired customer token",
+				Error:   err.Error(),
+			})
+		}
+
+		if sessionID := c.Params("session_id"); sessionID != "" && sessionID != customer.SessionID.String() {
+			return CustomerSessionForbidden(c)
+		}
+
+		c.Locals("customer", customer)
+		return c.Next()
+	}
+}
+
+// OptionalCustomer accepts requests without a customer token, but identifies
+// the customer when a valid one is sent
+func (m *CustomerMiddleware) OptionalCustomer() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token, ok := strings.CutPrefix(c.Get("Authorization"), "Bearer "); ok {
+			if customer, err := m.chatUsecase.ValidateCustomerToken(c.Context(), token); err == nil {
+				c.Locals("customer", customer)
+			}
+		}
+		return c.Next()
+	}
+}
+
+// GetCustomerFromContext returns the chat user and session of the customer
+// token, or nil on routes without RequireCustomer
+func GetCustomerFromContext(c *fiber.Ctx) *domain.CustomerIdentity {
+	customer, ok := c.Locals("customer").(*domain.CustomerIdentity)
+	if !ok {
+		return nil
+	}
+	return customer
+}
+
+// CustomerSessionForbidden rejects a customer acting on a session their token
+// was not issued for
+func CustomerSessionForbidden(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(domain.ApiResponse{
+		Success: false,
+		Message: "Insufficient permissions",
+		Error:   "customer token is not valid for this session",
+	})
+}
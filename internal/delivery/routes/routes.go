@@ -14,7 +14,12 @@ func SetupRoutes(
 	userHandler *handler.UserHandler,
 	emailHandler *handler.EmailHandler,
 	agentStatusHandler *handler.AgentStatusHandler,
+	mfaHandler *handler.MFAHandler,
+	permissionHandler *handler.PermissionHandler,
+	departmentHandler *handler.DepartmentHandler,
+	tagHandler *handler.TagHandler,
 	authMiddleware *middleware.AuthMiddleware,
+	customerMiddleware *middleware.CustomerMiddleware,
 ) {
 	// Health check
 	app.Get("/health", func(c *fiber.Ctx) error {
@@ -32,65 +37,126 @@ func SetupRoutes(
 	// API routes
 	api := app.Group("/api")
 
-	// Public chat routes (legacy for backward compatibility)
+	// Public chat routes (legacy for backward compatibility). Everything but
+	// starting a chat needs the customer token returned by start.
 	public := api.Group("/public")
-	public.Post("/chat/start", chatHandler.StartChat)
-	public.Post("/chat/message", chatHandler.SendMessage)
-	public.Get("/chat/session/:session_id/messages", chatHandler.GetSessionMessages)
+	public.Post("/chat/start", customerMiddleware.OptionalCustomer(), chatHandler.StartChat)
+	public.Post("/chat/message", customerMiddleware.RequireCustomer(), chatHandler.SendMessage)
+	public.Get("/chat/session/:session_id/messages", customerMiddleware.RequireCustomer(), chatHandler.GetSessionMessages)
 
 	// OSS Chat routes (public endpoints for OSS integration)
 	ossChat := api.Group("/chat")
-	ossChat.Post("/start", chatHandler.StartChat)
-	ossChat.Post("/contact", chatHandler.SetSessionContact)
-	ossChat.Post("/link-user", chatHandler.LinkOSSUser)
-	ossChat.Get("/history", chatHandler.GetChatHistory)
-	ossChat.Get("/session/:session_id", chatHandler.GetSession)
+	ossChat.Post("/start", customerMiddleware.OptionalCustomer(), chatHandler.StartChat)
+	ossChat.Post("/message", customerMiddleware.RequireCustomer(), chatHandler.SendMessage)
+	ossChat.Post("/token", customerMiddleware.RequireCustomer(), chatHandler.RefreshCustomerToken)
+	ossChat.Post("/contact", customerMiddleware.RequireCustomer(), chatHandler.SetSessionContact)
+	ossChat.Post("/link-user", customerMiddleware.RequireCustomer(), chatHandler.LinkOSSUser)
+	ossChat.Get("/history", customerMiddleware.RequireCustomer(), chatHandler.GetChatHistory)
+	ossChat.Get("/session/:session_id", customerMiddleware.RequireCustomer(), chatHandler.GetSession)
+	ossChat.Get("/session/:session_id/queue", customerMiddleware.RequireCustomer(), chatHandler.GetQueueStatus)
+	ossChat.Post("/session/:session_id/rating", customerMiddleware.RequireCustomer(), chatHandler.RateSession)
 
 	// Authentication routes
 	auth := api.Group("/auth")
 	auth.Post("/login", authHandler.Login)
+	auth.Post("/login/mfa", authHandler.CompleteMFALogin)
+	auth.Get("/sso/authorize", authHandler.SSOAuthorize)
+	auth.Post("/sso/callback", authHandler.SSOCallback)
 	auth.Post("/refresh", authHandler.RefreshToken)
+	auth.Post("/forgot-password", authHandler.ForgotPassword)
+	auth.Post("/reset-password", authHandler.ResetPassword)
+	auth.Post("/change-password", authMiddleware.RequireAuth(), authHandler.ChangePassword)
 	auth.Post("/logout", authMiddleware.RequireAuth(), authHandler.Logout)
 	auth.Get("/validate", authMiddleware.RequireAuth(), authHandler.ValidateSession)
 	auth.Get("/profile", authMiddleware.RequireAuth(), authHandler.GetProfile)
 	auth.Post("/register", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), authHandler.Register)
+	auth.Get("/sessions", authMiddleware.RequireAuth(), authHandler.GetSessions)
+	auth.Delete("/sessions/:session_id", authMiddleware.RequireAuth(), authHandler.TerminateSession)
+	auth.Post("/users/:id/revoke-tokens", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), authHandler.RevokeUserTokens)
+	auth.Delete("/users/:id/mfa", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), mfaHandler.ResetUserMFA)
+	auth.Post("/users/:id/unlock", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), authHandler.UnlockUser)
+	auth.Get("/users/:id/sessions", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), authHandler.GetUserSessions)
+	auth.Delete("/users/:id/sessions/:session_id", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), authHandler.TerminateUserSession)
+
+	// MFA routes
+	mfa := auth.Group("/mfa", authMiddleware.RequireAuth())
+	mfa.Get("/", mfaHandler.GetStatus)
+	mfa.Post("/enroll", mfaHandler.Enroll)
+	mfa.Post("/verify", mfaHandler.ConfirmEnrollment)
+	mfa.Post("/disable", mfaHandler.Disable)
+	mfa.Post("/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+	mfa.Get("/policy", authMiddleware.RequireAdmin(), mfaHandler.GetPolicies)
+	mfa.Put("/policy", authMiddleware.RequireAdmin(), mfaHandler.SetPolicy)
 
 	// Protected chat management routes
 	chatManagement := api.Group("/chat-management")
 	chatManagement.Use(authMiddleware.RequireAuth())
 
+	chatManagement.Get("/search", authMiddleware.RequirePermission("messages:search"), chatHandler.SearchMessages)
+
 	// Agent routes
 	agent := chatManagement.Group("/agent")
 	agent.Use(authMiddleware.RequireAgent())
-	agent.Post("/message", chatHandler.SendMessage)
-	agent.Post("/assign", chatHandler.AssignAgent)
-	agent.Post("/close", chatHandler.CloseSession)
+	agent.Post("/message", chatHandler.RequireSessionAccess("send_message"), chatHandler.SendMessage)
+	agent.Post("/assign", chatHandler.RequireSessionAccess("assign"), chatHandler.AssignAgent)
+	agent.Post("/close", chatHandler.RequireSessionAccess("close"), chatHandler.CloseSession)
+	agent.Post("/transfer", chatHandler.RequireSessionAccess("transfer"), chatHandler.TransferSession)
 	agent.Get("/sessions", chatHandler.GetAgentSessions)
-	agent.Get("/sessions/:id/connection-status", chatHandler.GetSessionConnectionStatus)
-	agent.Get("/sessions/:session_id", chatHandler.GetSession)
+	agent.Get("/sessions/:id/connection-status", chatHandler.RequireSessionAccess("view"), chatHandler.GetSessionConnectionStatus)
+	agent.Get("/sessions/:session_id", chatHandler.RequireSessionAccess("view"), chatHandler.GetSession)
+	agent.Post("/tags", chatHandler.RequireSessionAccess("tag"), tagHandler.AddTagToSession)
+	agent.Delete("/sessions/:session_id/tags/:tag_id", chatHandler.RequireSessionAccess("tag"), tagHandler.RemoveTagFromSession)
 
-	// Admin routes
+	// Admin and supervisor routes, supervisors are limited to their department
 	admin := chatManagement.Group("/admin")
-	admin.Use(authMiddleware.RequireAdmin())
-	admin.Get("/waiting", chatHandler.GetWaitingSessions)
-	admin.Get("/active", chatHandler.GetActiveSessions)
-	admin.Post("/assign", chatHandler.AssignAgent)
-	admin.Post("/close", chatHandler.CloseSession)
-	admin.Get("/sessions", chatHandler.GetSessions)
+	admin.Get("/waiting", authMiddleware.RequirePermission("sessions:view"), chatHandler.GetWaitingSessions)
+	admin.Get("/active", authMiddleware.RequirePermission("sessions:view"), chatHandler.GetActiveSessions)
+	admin.Post("/assign", authMiddleware.RequirePermission("sessions:assign"), chatHandler.AssignAgent)
+	admin.Post("/close", authMiddleware.RequirePermission("sessions:close"), chatHandler.CloseSession)
+	admin.Post("/transfer", authMiddleware.RequirePermission("sessions:assign"), chatHandler.TransferSession)
+	admin.Get("/sessions", authMiddleware.RequirePermission("sessions:view"), chatHandler.GetSessions)
 	// admin.Get("/sessions/:id/connection-status", chatHandler.GetSessionConnectionStatus)
 	// admin.Get("/sessions/:id", chatHandler.GetSession)
 
+	// Permission routes
+	permissions := api.Group("/permissions")
+	permissions.Use(authMiddleware.RequireAuth(), authMiddleware.RequireAdmin())
+	permissions.Get("/", permissionHandler.GetPermissions)
+	permissions.Put("/roles/:role", permissionHandler.SetRolePermissions)
+
+	// Department routes
+	departments := api.Group("/departments")
+	departments.Use(authMiddleware.RequireAuth(), authMiddleware.RequireAdmin())
+	departments.Get("/", departmentHandler.GetDepartments)
+	departments.Post("/", departmentHandler.CreateDepartment)
+	departments.Get("/:id", departmentHandler.GetDepartment)
+	departments.Put("/:id", departmentHandler.UpdateDepartment)
+	departments.Delete("/:id", departmentHandler.DeleteDepartment)
+
+	// Tag routes, the catalogue is readable by all staff
+	tags := api.Group("/tags")
+	tags.Use(authMiddleware.RequireAuth())
+	tags.Get("/", tagHandler.GetTags)
+	tags.Post("/", authMiddleware.RequireAdmin(), tagHandler.CreateTag)
+	tags.Put("/:id", authMiddleware.RequireAdmin(), tagHandler.UpdateTag)
+	tags.Delete("/:id", authMiddleware.RequireAdmin(), tagHandler.DeleteTag)
+
 	// User routes
 	users := api.Group("/users")
 	users.Use(authMiddleware.RequireAuth())
 	users.Get("/", userHandler.GetUsers)
 	users.Get("/agents", userHandler.GetAgents)
 	users.Get("/:id", userHandler.GetUser)
+	users.Put("/:id", authMiddleware.RequireAdmin(), userHandler.UpdateUser)
+	users.Put("/:id/active", authMiddleware.RequireAdmin(), userHandler.SetUserActive)
+	users.Delete("/:id", authMiddleware.RequireAdmin(), userHandler.DeleteUser)
 
 	// Analytics routes
 	analytics := api.Group("/analytics")
 	analytics.Use(authMiddleware.RequireAuth())
 	analytics.Get("/dashboard", analyticsHandler.GetDashboardStats)
+	analytics.Get("/agent-performance", analyticsHandler.GetAgentPerformance)
+	analytics.Get("/response-times", analyticsHandler.GetSessionTimings)
 	analytics.Get("/", analyticsHandler.GetAnalytics)
 
 	// Email routes
@@ -98,7 +164,6 @@ func SetupRoutes(
 	email.Use(authMiddleware.RequireAuth())
 	email.Post("/send", emailHandler.SendEmail)
 	email.Post("/welcome", emailHandler.SendWelcomeEmail)
-	email.Post("/password-reset", emailHandler.SendPasswordResetEmail)
 	email.Post("/chat-transcript", emailHandler.SendChatTranscriptEmail)
 	email.Post("/custom", emailHandler.SendCustomEmail)
 
@@ -17,7 +17,38 @@ type LoginResponse struct {
 	RefreshToken string    `json:"refresh_token"`
 	ExpiresIn    int64     `json:"expires_in"`
 	ExpiresAt    time.Time `json:"expires_at"`
+	SessionID    string    `json:"session_id"`
 	User         *User     `json:"user"`
+
+	// Set instead of the tokens when the password was right but a second
+	// factor is needed; complete the login with the MFA token
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+	// Set when the role requires MFA and the user has not enrolled yet. Only
+	// /api/auth routes can be used until enrollment is done.
+	MFAEnrollmentRequired bool `json:"mfa_enrollment_required,omitempty"`
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"` // TOTP or recovery code
+}
+
+type SSOAuthorizeResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+}
+
+type SSOCallbackRequest struct {
+	Code  string `json:"code" validate:"required"`
+	State string `json:"state" validate:"required"`
+}
+
+// OIDCAuthState is kept server-side between sending the browser to the IdP
+// and the callback, keyed by the state parameter
+type OIDCAuthState struct {
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
 }
 
 type RefreshTokenRequest struct {
@@ -35,10 +66,92 @@ type RegisterRequest struct {
 	Email        string     `json:"email" validate:"required,email"`
 	Password     string     `json:"password" validate:"required,min=6"`
 	Name         string     `json:"name" validate:"required"`
-	Role         string     `json:"role" validate:"required,oneof=admin agent"`
+	Role         string     `json:"role" validate:"required,oneof=admin supervisor agent"`
 	DepartmentID *uuid.UUID `json:"department_id"`
 }
 
+type UpdateUserRequest struct {
+	Email        string     `json:"email" validate:"required,email"`
+	Name         string     `json:"name" validate:"required"`
+	Role         string     `json:"role" validate:"required,oneof=admin supervisor agent"`
+	DepartmentID *uuid.UUID `json:"department_id"`
+}
+
+type SetUserActiveRequest struct {
+	IsActive bool `json:"is_active"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=6"`
+}
+
+// MFA DTOs
+type MFAStatusResponse struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+type MFAEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI to render as QR code
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type MFADisableRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type SetMFAPolicyRequest struct {
+	Role     string `json:"role" validate:"required"`
+	Required bool   `json:"required"`
+}
+
+// Permission DTOs
+type RolePermissionsResponse struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+type PermissionsResponse struct {
+	Permissions []*Permission              `json:"permissions"`
+	Roles       []*RolePermissionsResponse `json:"roles"`
+}
+
+type SetRolePermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+// LoginSession is one signed-in device of a user. It lives as long as the
+// refresh tokens of that login.
+type LoginSession struct {
+	SessionID string    `json:"session_id"`
+	UserID    string    `json:"user_id"`
+	ClientIP  string    `json:"client_ip"`
+	UserAgent string    `json:"user_agent"`
+	LoginTime time.Time `json:"login_time"`
+	LastSeen  time.Time `json:"last_seen"`
+	Current   bool      `json:"current"`
+}
+
 // Department DTOs
 type CreateDepartmentRequest struct {
 	Name        string `json:"name" validate:"required"`
@@ -53,20 +166,40 @@ type UpdateDepartmentRequest struct {
 
 // Chat Session DTOs for OSS System
 type StartChatRequest struct {
-	BrowserUUID *uuid.UUID `json:"browser_uuid"` // For anonymous users
-	OSSUserID   *string    `json:"oss_user_id"`  // For logged-in OSS users
-	Email       *string    `json:"email"`        // For logged-in users
-	Topic       string     `json:"topic" validate:"required"`
-	Priority    string     `json:"priority" validate:"oneof=low normal high urgent"`
-	UserAgent   *string    `json:"user_agent"`
+	BrowserUUID  *uuid.UUID `json:"browser_uuid"` // For anonymous users
+	OSSUserID    *string    `json:"oss_user_id"`  // For logged-in OSS users
+	Email        *string    `json:"email"`        // For logged-in users
+	Topic        string     `json:"topic" validate:"required"`
+	Priority     string     `json:"priority" validate:"oneof=low normal high urgent"`
+	UserAgent    *string    `json:"user_agent"`
+	DepartmentID *uuid.UUID `json:"department_id"` // Route directly to a department
+	Category     *string    `json:"category"`      // OSS licensing area, resolved via topic mappings
+	// Customer is set when a valid customer token was sent. Only then is an
+	// existing chat user of the browser UUID or OSS user ID reused.
+	Customer *CustomerIdentity `json:"-"`
 }
 
 type StartChatResponse struct {
-	SessionID       uuid.UUID `json:"session_id"`
-	ChatUserID      uuid.UUID `json:"chat_user_id"`
-	Status          string    `json:"status"`
-	Message         string    `json:"message"`
-	RequiresContact bool      `json:"requires_contact"` // True if contact info needed
+	SessionID       uuid.UUID  `json:"session_id"`
+	ChatUserID      uuid.UUID  `json:"chat_user_id"`
+	DepartmentID    *uuid.UUID `json:"department_id,omitempty"`
+	Status          string     `json:"status"`
+	Message         string     `json:"message"`
+	RequiresContact bool       `json:"requires_contact"` // True if contact info needed
+	// CustomerToken authenticates the widget on the other public chat routes
+	CustomerToken          string    `json:"customer_token"`
+	CustomerTokenExpiresAt time.Time `json:"customer_token_expires_at"`
+}
+
+// CustomerIdentity is the chat user and session a customer token was issued for
+type CustomerIdentity struct {
+	ChatUserID uuid.UUID
+	SessionID  uuid.UUID
+}
+
+type CustomerTokenResponse struct {
+	CustomerToken string    `json:"customer_token"`
+	ExpiresAt     time.Time `json:"expires_at"`
 }
 
 type SetSessionContactRequest struct {
@@ -95,10 +228,9 @@ type LinkOSSUserResponse struct {
 }
 
 type GetChatHistoryRequest struct {
-	BrowserUUID *uuid.UUID `json:"browser_uuid"` // For anonymous users
-	OSSUserID   *string    `json:"oss_user_id"`  // For logged-in users
-	Limit       int        `json:"limit"`
-	Offset      int        `json:"offset"`
+	ChatUserID uuid.UUID `json:"-"` // From the customer token
+	Limit      int       `json:"limit"`
+	Offset     int       `json:"offset"`
 }
 
 type GetChatHistoryResponse struct {
@@ -134,6 +266,17 @@ type TransferChatRequest struct {
 	Reason          string     `json:"reason"`
 }
 
+// Waiting queue DTOs
+type QueueStatusResponse struct {
+	SessionID            uuid.UUID  `json:"session_id"`
+	Status               string     `json:"status"`
+	InQueue              bool       `json:"in_queue"`
+	Position             int        `json:"position"` // 1-based, 0 when not queued
+	QueueLength          int        `json:"queue_length"`
+	EstimatedWaitSeconds int        `json:"estimated_wait_seconds"`
+	DepartmentID         *uuid.UUID `json:"department_id,omitempty"`
+}
+
 // Chat Message DTOs
 type SendMessageRequest struct {
 	SessionID   uuid.UUID `json:"session_id" validate:"required"`
@@ -154,6 +297,11 @@ type CreateTagRequest struct {
 	Color string `json:"color"`
 }
 
+type UpdateTagRequest struct {
+	Name  string `json:"name" validate:"required"`
+	Color string `json:"color"`
+}
+
 type AddTagToSessionRequest struct {
 	SessionID uuid.UUID `json:"session_id" validate:"required"`
 	TagID     uuid.UUID `json:"tag_id" validate:"required"`
@@ -174,6 +322,7 @@ type AnalyticsResponse struct {
 	TotalMessages       int                   `json:"total_messages"`
 	SessionsByStatus    map[string]int        `json:"sessions_by_status"`
 	SessionsByPriority  map[string]int        `json:"sessions_by_priority"`
+	SessionsByTag       map[string]int        `json:"sessions_by_tag"`
 	DailyAnalytics      []DailyAnalytics      `json:"daily_analytics"`
 	AgentPerformance    []AgentPerformance    `json:"agent_performance"`
 	DepartmentAnalytics []DepartmentAnalytics `json:"department_analytics"`
@@ -191,7 +340,40 @@ type AgentPerformance struct {
 	TotalSessions       int     `json:"total_sessions"`
 	CompletedSessions   int     `json:"completed_sessions"`
 	AverageResponseTime float64 `json:"average_response_time"`
+	AverageHandleTime   float64 `json:"average_handle_time"`
 	TotalMessages       int     `json:"total_messages"`
+	AverageRating       float64 `json:"average_rating"`
+	TotalRatings        int     `json:"total_ratings"`
+}
+
+// AgentSessionStats aggregates the sessions handled by one agent
+type AgentSessionStats struct {
+	AgentID             string
+	TotalSessions       int
+	CompletedSessions   int
+	TotalMessages       int
+	AverageResponseTime float64 // seconds to first agent message
+	AverageHandleTime   float64 // seconds from start to close
+}
+
+// SessionMetricsFilter narrows session timing metrics; nil fields are not filtered
+type SessionMetricsFilter struct {
+	StartDate    *time.Time
+	EndDate      *time.Time // exclusive
+	DepartmentID *string
+	AgentID      *string
+}
+
+// SessionTimingStats holds average session timings in seconds
+type SessionTimingStats struct {
+	AverageResponseTime float64 `json:"average_response_time"`
+	AverageHandleTime   float64 `json:"average_handle_time"`
+}
+
+// RatingSummary aggregates CSAT ratings
+type RatingSummary struct {
+	AverageRating float64 `json:"average_rating"`
+	TotalRatings  int     `json:"total_ratings"`
 }
 
 type DepartmentAnalytics struct {
@@ -253,22 +435,67 @@ type ApiResponse struct {
 }
 
 // Session Management DTOs
+
+// SessionListRequest filters session lists. Search matches the topic, contact
+// name and email, and message bodies. SortBy is one of created_at,
+// started_at, ended_at, updated_at, status or priority.
 type SessionListRequest struct {
 	Status       string     `json:"status" query:"status"`
 	AgentID      *uuid.UUID `json:"agent_id" query:"agent_id"`
 	DepartmentID *uuid.UUID `json:"department_id" query:"department_id"`
 	CustomerID   *uuid.UUID `json:"customer_id" query:"customer_id"`
+	TagID        *uuid.UUID `json:"tag_id" query:"tag_id"`
 	Priority     string     `json:"priority" query:"priority"`
 	DateFrom     *time.Time `json:"date_from" query:"date_from"`
-	DateTo       *time.Time `json:"date_to" query:"date_to"`
+	DateTo       *time.Time `json:"date_to" query:"date_to"` // exclusive
 	PaginationRequest
 }
 
+// MessageSearchRequest searches message bodies. Query takes web search
+// syntax: quoted phrases, "or" and -word.
+type MessageSearchRequest struct {
+	Query        string
+	DepartmentID *uuid.UUID
+	Limit        int
+	Offset       int
+}
+
+// MessageSearchResult is a message matching a search. Snippet holds the
+// matching parts of the message as HTML, escaped, with the matched words in
+// <mark> tags.
+type MessageSearchResult struct {
+	MessageID     string    `json:"message_id"`
+	SessionID     string    `json:"session_id"`
+	SenderType    string    `json:"sender_type"`
+	Snippet       string    `json:"snippet"`
+	Rank          float64   `json:"rank"`
+	CreatedAt     time.Time `json:"created_at"`
+	SessionTopic  string    `json:"session_topic"`
+	SessionStatus string    `json:"session_status"`
+	DepartmentID  string    `json:"department_id,omitempty"`
+}
+
+type RateSessionRequest struct {
+	Rating   int    `json:"rating" validate:"required,min=1,max=5"`
+	Feedback string `json:"feedback"`
+}
+
 type CloseSessionRequest struct {
 	SessionID uuid.UUID `json:"session_id" validate:"required"`
 	Reason    string    `json:"reason"`
 	Rating    *int      `json:"rating" validate:"omitempty,min=1,max=5"`
 	Feedback  string    `json:"feedback"`
+	// RatingRelayed confirms the rating is the customer's, given to the staff
+	// member closing the session (e.g. over the phone). It is required with Rating.
+	RatingRelayed bool `json:"rating_relayed"`
+}
+
+// IdleSession is an open session together with its last customer or agent activity
+type IdleSession struct {
+	SessionID      string
+	Status         string
+	LastActivityAt time.Time
+	WarnedAt       *time.Time // latest idle warning, if any
 }
 
 // Analytics related structures for OSS support system
@@ -277,8 +504,11 @@ type DashboardStats struct {
 	WaitingSessions     int             `json:"waitingSessions"`
 	CompletedToday      int             `json:"completedToday"`
 	AverageResponseTime int             `json:"averageResponseTime"` // in seconds
+	AverageHandleTime   int             `json:"averageHandleTime"`   // in seconds
 	TotalAgents         int             `json:"totalAgents"`
 	OnlineAgents        int             `json:"onlineAgents"`
+	AverageRating       float64         `json:"averageRating"` // CSAT over the last 30 days
+	TotalRatings        int             `json:"totalRatings"`
 	TopQuestions        []QuestionStats `json:"topQuestions"`
 	OSSCategories       []CategoryStats `json:"ossCategories"`
 }
@@ -2,7 +2,11 @@ package domain
 
 // Re-export entities from shared package for backward compatibility
 import (
+	"database/sql"
+	"time"
+
 	"github.com/novianakbar/livechat-shared/entities"
+	"gorm.io/plugin/soft_delete"
 )
 
 // Re-export all entities
@@ -17,3 +21,133 @@ type ChatTag = entities.ChatTag
 type ChatSessionTag = entities.ChatSessionTag
 type AgentStatus = entities.AgentStatus
 type ChatAnalytics = entities.ChatAnalytics
+
+// Backend-only entities, not part of the shared package
+
+// TopicDepartmentMapping routes new chats about a topic to a department
+type TopicDepartmentMapping struct {
+	ID           string                `gorm:"primaryKey;type:varchar(255)" json:"id"`
+	Topic        string                `gorm:"type:varchar(255);not null" json:"topic"`
+	DepartmentID string                `gorm:"type:varchar(255);not null" json:"department_id"`
+	Department   *Department           `gorm:"foreignKey:DepartmentID" json:"department,omitempty"`
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+	DeletedAt    soft_delete.DeletedAt `gorm:"default:0" json:"-"`
+}
+
+func (TopicDepartmentMapping) TableName() string {
+	return "topic_department_mappings"
+}
+
+// ChatAnalyticsRollup is a chat_analytics row with the number of sessions that
+// got an agent reply, which AverageResponseTime is averaged over
+type ChatAnalyticsRollup struct {
+	ChatAnalytics
+	RespondedSessions int `gorm:"not null;default:0" json:"responded_sessions"`
+}
+
+func (ChatAnalyticsRollup) TableName() string {
+	return "chat_analytics"
+}
+
+// SessionRating is the customer satisfaction (CSAT) score given to a closed session
+type SessionRating struct {
+	ID        string                `gorm:"primaryKey;type:varchar(255)" json:"id"`
+	SessionID string                `gorm:"type:varchar(255);not null" json:"session_id"`
+	AgentID   sql.NullString        `gorm:"type:varchar(255)" json:"agent_id"`
+	Rating    int                   `gorm:"not null" json:"rating"`
+	Feedback  sql.NullString        `gorm:"type:text" json:"feedback"`
+	RelayedBy sql.NullString        `gorm:"type:varchar(255)" json:"relayed_by"` // staff user who entered the customer's rating
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	DeletedAt soft_delete.DeletedAt `gorm:"default:0" json:"-"`
+}
+
+func (SessionRating) TableName() string {
+	return "session_ratings"
+}
+
+// PasswordResetToken is a single-use password reset link. Only the SHA-256
+// hash of the token is stored; the token itself is only sent by email.
+type PasswordResetToken struct {
+	ID        string                `gorm:"primaryKey;type:varchar(255)" json:"id"`
+	UserID    string                `gorm:"type:varchar(255);not null" json:"user_id"`
+	TokenHash string                `gorm:"type:varchar(64);not null" json:"-"`
+	ExpiresAt time.Time             `gorm:"not null" json:"expires_at"`
+	UsedAt    sql.NullTime          `json:"used_at"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	DeletedAt soft_delete.DeletedAt `gorm:"default:0" json:"-"`
+}
+
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
+
+// UserMFA holds the TOTP secret of a staff user. The secret is pending until
+// the first code is verified, then Enabled is set.
+type UserMFA struct {
+	ID           string                `gorm:"primaryKey;type:varchar(255)" json:"id"`
+	UserID       string                `gorm:"type:varchar(255);not null" json:"user_id"`
+	Secret       string                `gorm:"type:varchar(255);not null" json:"-"`
+	Enabled      bool                  `gorm:"not null;default:false" json:"enabled"`
+	EnabledAt    sql.NullTime          `json:"enabled_at"`
+	LastUsedStep int64                 `gorm:"not null;default:0" json:"-"` // last accepted TOTP time step, against replay
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+	DeletedAt    soft_delete.DeletedAt `gorm:"default:0" json:"-"`
+}
+
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
+
+// UserRecoveryCode is a single-use MFA recovery code, stored hashed
+type UserRecoveryCode struct {
+	ID        string                `gorm:"primaryKey;type:varchar(255)" json:"id"`
+	UserID    string                `gorm:"type:varchar(255);not null" json:"user_id"`
+	CodeHash  string                `gorm:"type:varchar(64);not null" json:"-"`
+	UsedAt    sql.NullTime          `json:"used_at"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	DeletedAt soft_delete.DeletedAt `gorm:"default:0" json:"-"`
+}
+
+func (UserRecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
+
+// MFAPolicy tells whether users of a role must use two-factor authentication
+type MFAPolicy struct {
+	Role      string    `gorm:"primaryKey;type:varchar(50)" json:"role"`
+	Required  bool      `gorm:"not null;default:false" json:"required"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (MFAPolicy) TableName() string {
+	return "mfa_policies"
+}
+
+// Permission is an action that can be granted to roles, e.g. "sessions:assign"
+type Permission struct {
+	Name        string         `gorm:"primaryKey;type:varchar(100)" json:"name"`
+	Description sql.NullString `gorm:"type:text" json:"description"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+func (Permission) TableName() string {
+	return "permissions"
+}
+
+// RolePermission grants a permission to every user of a role
+type RolePermission struct {
+	Role       string    `gorm:"primaryKey;type:varchar(50)" json:"role"`
+	Permission string    `gorm:"primaryKey;type:varchar(100)" json:"permission"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (RolePermission) TableName() string {
+	return "role_permissions"
+}
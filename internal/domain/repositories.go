@@ -22,6 +22,7 @@ type UserRepository interface {
 	GetByID(ctx context.Context, id string) (*User, error)
 	GetByEmail(ctx context.Context, email string) (*User, error)
 	Update(ctx context.Context, user *User) error
+	UpdatePassword(ctx context.Context, id string, passwordHash string) error
 	Delete(ctx context.Context, id string) error
 	GetAgentsByDepartment(ctx context.Context, departmentID string) ([]*User, error)
 	GetAvailableAgents(ctx context.Context, departmentID *string) ([]*User, error)
@@ -40,6 +41,14 @@ type DepartmentRepository interface {
 	GetAll(ctx context.Context) ([]*Department, error)
 	Update(ctx context.Context, department *Department) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	// CountUsers counts the staff of each department, only of the role unless empty
+	CountUsers(ctx context.Context, departmentIDs []string, role string) (map[string]int, error)
+}
+
+// TopicDepartmentMappingRepository interface for topic based routing
+type TopicDepartmentMappingRepository interface {
+	GetByTopic(ctx context.Context, topic string) (*TopicDepartmentMapping, error)
+	GetAll(ctx context.Context) ([]*TopicDepartmentMapping, error)
 }
 
 // ChatUserRepository interface for chat user operations
@@ -70,21 +79,29 @@ type ChatSessionRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*ChatSession, error)
 	GetByChatUserID(ctx context.Context, chatUserID uuid.UUID) ([]*ChatSession, error)
 	GetByAgentID(ctx context.Context, agentID uuid.UUID) ([]*ChatSession, error)
-	GetActiveSessions(ctx context.Context) ([]*ChatSession, error)
-	GetWaitingSessions(ctx context.Context) ([]*ChatSession, error)
+	GetActiveSessions(ctx context.Context, departmentID *uuid.UUID) ([]*ChatSession, error)
+	GetWaitingSessions(ctx context.Context, departmentID *uuid.UUID) ([]*ChatSession, error)
 	Update(ctx context.Context, session *ChatSession) error
 	Close(ctx context.Context, sessionID uuid.UUID) error
+	AssignIfWaiting(ctx context.Context, sessionID uuid.UUID, agentID string) (bool, error)
 	GetSessionsByStatus(ctx context.Context, status string) ([]*ChatSession, error)
 	GetSessionsByDateRange(ctx context.Context, start, end time.Time) ([]*ChatSession, error)
-	GetWithPagination(ctx context.Context, offset, limit int, status string, agentID, departmentID *uuid.UUID) ([]*ChatSession, error)
+	GetWithPagination(ctx context.Context, offset, limit int, filter *SessionListRequest) ([]*ChatSession, error)
+	CountWithFilter(ctx context.Context, filter *SessionListRequest) (int, error)
 	GetSessionsWithMessages(ctx context.Context, chatUserID uuid.UUID, limit, offset int) ([]*ChatSession, error)
+	CountByChatUserID(ctx context.Context, chatUserID uuid.UUID) (int, error)
 	GetSessionHistory(ctx context.Context, chatUserID uuid.UUID, limit, offset int) ([]*ChatSession, error)
 	Count(ctx context.Context, status string, agentID, departmentID *uuid.UUID) (int, error)
+	CountOpenByAgents(ctx context.Context, agentIDs []string) (map[string]int, error)
+	GetIdleSessions(ctx context.Context, status string, idleSince time.Time) ([]*IdleSession, error)
+	GetAgentSessionStats(ctx context.Context, start, end time.Time) (map[string]AgentSessionStats, error)
+	CountGroupedBy(ctx context.Context, column string, filter SessionMetricsFilter) (map[string]int, error)
 	// Analytics methods
 	CountByStatus(ctx context.Context, status string) (int64, error)
 	CountCompletedSince(ctx context.Context, since time.Time) (int64, error)
-	GetAverageResponseTime(ctx context.Context) (float64, error)
-	GetOSSCategoriesStats(ctx context.Context) ([]CategoryStats, error)
+	GetAverageResponseTime(ctx context.Context, filter SessionMetricsFilter) (float64, error)
+	GetAverageHandleTime(ctx context.Context, filter SessionMetricsFilter) (float64, error)
+	GetOSSCategoriesStats(ctx context.Context, start, end time.Time) ([]CategoryStats, error)
 }
 
 // ChatMessageRepository interface for chat message operations
@@ -96,7 +113,57 @@ type ChatMessageRepository interface {
 	Delete(ctx context.Context, id uuid.UUID) error
 	GetMessagesByDateRange(ctx context.Context, start, end time.Time) ([]*ChatMessage, error)
 	// Analytics methods
-	GetTopQuestions(ctx context.Context, limit int) ([]QuestionStats, error)
+	GetTopQuestions(ctx context.Context, start, end time.Time, limit int) ([]QuestionStats, error)
+	// Search finds customer and agent messages matching a full-text query,
+	// best matches first, and returns a page of them with the total
+	Search(ctx context.Context, req *MessageSearchRequest) ([]MessageSearchResult, int, error)
+}
+
+// SessionRatingRepository interface for CSAT rating operations
+type SessionRatingRepository interface {
+	Create(ctx context.Context, rating *SessionRating) (bool, error)
+	GetBySessionID(ctx context.Context, sessionID uuid.UUID) (*SessionRating, error)
+	GetSummary(ctx context.Context, start, end time.Time) (*RatingSummary, error)
+	GetSummaryByAgent(ctx context.Context, start, end time.Time) (map[string]RatingSummary, error)
+}
+
+// PasswordResetTokenRepository interface for password reset token operations
+type PasswordResetTokenRepository interface {
+	Create(ctx context.Context, token *PasswordResetToken) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*PasswordResetToken, error)
+	MarkUsed(ctx context.Context, id string) (bool, error)
+	InvalidateForUser(ctx context.Context, userID string) error
+}
+
+// UserMFARepository interface for TOTP secret operations
+type UserMFARepository interface {
+	GetByUserID(ctx context.Context, userID string) (*UserMFA, error)
+	Save(ctx context.Context, mfa *UserMFA) error
+	UpdateLastUsedStep(ctx context.Context, userID string, step int64) (bool, error)
+	DeleteByUserID(ctx context.Context, userID string) error
+}
+
+// UserRecoveryCodeRepository interface for MFA recovery code operations
+type UserRecoveryCodeRepository interface {
+	ReplaceForUser(ctx context.Context, userID string, codes []*UserRecoveryCode) error
+	Use(ctx context.Context, userID, codeHash string) (bool, error)
+	CountUnused(ctx context.Context, userID string) (int64, error)
+	DeleteByUserID(ctx context.Context, userID string) error
+}
+
+// MFAPolicyRepository interface for MFA policy operations
+type MFAPolicyRepository interface {
+	GetAll(ctx context.Context) ([]*MFAPolicy, error)
+	IsRequired(ctx context.Context, role string) (bool, error)
+	SetRequired(ctx context.Context, role string, required bool) error
+}
+
+// PermissionRepository interface for role permission operations
+type PermissionRepository interface {
+	GetAll(ctx context.Context) ([]*Permission, error)
+	GetRolePermissions(ctx context.Context) ([]*RolePermission, error)
+	// SetRolePermissions replaces all permissions of the role
+	SetRolePermissions(ctx context.Context, role string, permissions []string) error
 }
 
 // ChatLogRepository interface for chat log operations
@@ -119,6 +186,9 @@ type ChatTagRepository interface {
 type ChatSessionTagRepository interface {
 	Create(ctx context.Context, sessionTag *ChatSessionTag) error
 	GetBySessionID(ctx context.Context, sessionID uuid.UUID) ([]*ChatSessionTag, error)
+	// GetBySessionIDs returns the tags of several sessions with Tag preloaded
+	GetBySessionIDs(ctx context.Context, sessionIDs []string) ([]*ChatSessionTag, error)
+	Delete(ctx context.Context, sessionID, tagID uuid.UUID) error
 	DeleteBySessionID(ctx context.Context, sessionID uuid.UUID) error
 	DeleteByTagID(ctx context.Context, tagID uuid.UUID) error
 }
@@ -135,10 +205,11 @@ type AgentStatusRepository interface {
 // ChatAnalyticsRepository interface for chat analytics operations
 type ChatAnalyticsRepository interface {
 	Create(ctx context.Context, analytics *ChatAnalytics) error
-	GetByDateRange(ctx context.Context, start, end time.Time) ([]*ChatAnalytics, error)
-	GetByAgentAndDateRange(ctx context.Context, agentID uuid.UUID, start, end time.Time) ([]*ChatAnalytics, error)
-	GetByDepartmentAndDateRange(ctx context.Context, departmentID uuid.UUID, start, end time.Time) ([]*ChatAnalytics, error)
-	UpdateOrCreate(ctx context.Context, analytics *ChatAnalytics) error
+	GetByDateRange(ctx context.Context, start, end time.Time) ([]*ChatAnalyticsRollup, error)
+	GetByAgentAndDateRange(ctx context.Context, agentID uuid.UUID, start, end time.Time) ([]*ChatAnalyticsRollup, error)
+	GetByDepartmentAndDateRange(ctx context.Context, departmentID uuid.UUID, start, end time.Time) ([]*ChatAnalyticsRollup, error)
+	ReplaceDay(ctx context.Context, day time.Time, analytics []*ChatAnalyticsRollup) error
+	AggregateDay(ctx context.Context, day time.Time) ([]*ChatAnalyticsRollup, error)
 }
 
 // EmailService interface for email operations
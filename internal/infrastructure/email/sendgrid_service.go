@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/url"
 	"strings"
 
 	"github.com/google/uuid"
@@ -150,8 +151,7 @@ func (s *sendgridService) SendWelcomeEmail(ctx context.Context, to string, name
 
 // SendPasswordResetEmail sends password reset email
 func (s *sendgridService) SendPasswordResetEmail(ctx context.Context, to string, resetToken string) (*domain.EmailResponse, error) {
-	// You should replace this with your actual reset URL
-	resetURL := fmt.Sprintf("https://yourapp.com/reset-password?token=%s", resetToken)
+	resetURL := fmt.Sprintf("%s?token=%s", s.config.PasswordResetURL, url.QueryEscape(resetToken))
 
 	template := &domain.EmailTemplate{
 		Name:    "password_reset",
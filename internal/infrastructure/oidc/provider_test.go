@@ -0,0 +1,300 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID     = "livechat"
+	testCode         = "auth-code"
+	testCodeVerifier = "verifier-0123456789-0123456789-0123456789"
+	testNonce        = "nonce-1"
+)
+
+// stubIdP is an OpenID Connect provider serving discovery, a key set and a
+// token endpoint. The token endpoint only redeems testCode together with
+// testCodeVerifier and answers with the ID token built by idToken.
+type stubIdP struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	// issuer published in the discovery document, the server URL when empty
+	issuer string
+	// idToken returns the ID token the token endpoint hands out
+	idToken func(s *stubIdP) string
+
+	receivedVerifier string
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	s := &stubIdP{t: t, key: key}
+	s.idToken = func(s *stubIdP) string {
+		return s.sign("key-1", s.claims())
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.handleDiscovery)
+	mux.HandleFunc("/jwks", s.handleJWKS)
+	mux.HandleFunc("/token", s.handleToken)
+	s.server = httptest.NewServer(mux)
+	t.Cleanup(s.server.Close)
+
+	return s
+}
+
+func (s *stubIdP) provider() *Provider {
+	return NewProvider(s.server.URL, testClientID, "", "http://localhost/callback", []string{"openid", "email"})
+}
+
+// claims returns valid ID token claims for the test client
+func (s *stubIdP) claims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   s.server.URL,
+		"aud":   testClientID,
+		"sub":   "idp-user-1",
+		"email": "agent@example.com",
+		"nonce": testNonce,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+func (s *stubIdP) sign(kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		s.t.Fatalf("SignedString: %v", err)
+	}
+	return signed
+}
+
+func (s *stubIdP) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	issuer := s.issuer
+	if issuer == "" {
+		issuer = s.server.URL
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 issuer,
+		"authorization_endpoint": s.server.URL + "/authorize",
+		"token_endpoint":         s.server.URL + "/token",
+		"jwks_uri":               s.server.URL + "/jwks",
+	})
+}
+
+// handleJWKS publishes an unrelated key next to the signing key, so tokens
+// only verify when the key is picked by its ID
+func (s *stubIdP) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		s.t.Errorf("GenerateKey: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{
+			rsaJWK("key-0", &other.PublicKey),
+			rsaJWK("key-1", &s.key.PublicKey),
+		},
+	})
+}
+
+func (s *stubIdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	s.receivedVerifier = r.PostForm.Get("code_verifier")
+	if r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("code") != testCode ||
+		s.receivedVerifier != testCodeVerifier {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"id_token": s.idToken(s)})
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kid": kid,
+		"kty": "RSA",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestAuthCodeURLSendsS256Challenge(t *testing.T) {
+	idp := newStubIdP(t)
+
+	authURL, err := idp.provider().AuthCodeURL(context.Background(), "state-1", testNonce, testCodeVerifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+
+	if !strings.HasPrefix(authURL, idp.server.URL+"/authorize?") {
+		t.Fatalf("AuthCodeURL = %s, want the discovered authorization endpoint", authURL)
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse %s: %v", authURL, err)
+	}
+	query := parsed.Query()
+
+	challenge := sha256.Sum256([]byte(testCodeVerifier))
+	if got, want := query.Get("code_challenge"), base64.RawURLEncoding.EncodeToString(challenge[:]); got != want {
+		t.Errorf("code_challenge = %s, want %s", got, want)
+	}
+	if got := query.Get("code_challenge_method"); got != "S256" {
+		t.Errorf("code_challenge_method = %s, want S256", got)
+	}
+	if query.Has("code_verifier") {
+		t.Error("the code verifier was sent to the authorization endpoint")
+	}
+	if got := query.Get("nonce"); got != testNonce {
+		t.Errorf("nonce = %s, want %s", got, testNonce)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	idp := newStubIdP(t)
+
+	claims, err := idp.provider().Exchange(context.Background(), testCode, testCodeVerifier, testNonce)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	if idp.receivedVerifier != testCodeVerifier {
+		t.Errorf("token endpoint got code_verifier %q, want %q", idp.receivedVerifier, testCodeVerifier)
+	}
+	if sub, _ := claims["sub"].(string); sub != "idp-user-1" {
+		t.Errorf("sub = %q, want idp-user-1", sub)
+	}
+	if email, _ := claims["email"].(string); email != "agent@example.com" {
+		t.Errorf("email = %q, want agent@example.com", email)
+	}
+}
+
+func TestExchangeRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		issuer   string
+		idToken  func(s *stubIdP) string
+		verifier string
+		wantErr  string
+	}{
+		{
+			name:    "discovery issuer mismatch",
+			issuer:  "https://idp.example.com",
+			wantErr: "does not match",
+		},
+		{
+			name:     "wrong code verifier",
+			verifier: "another-verifier",
+			wantErr:  "invalid_grant",
+		},
+		{
+			name: "nonce mismatch",
+			idToken: func(s *stubIdP) string {
+				claims := s.claims()
+				claims["nonce"] = "nonce-2"
+				return s.sign("key-1", claims)
+			},
+			wantErr: "nonce mismatch",
+		},
+		{
+			name: "wrong audience",
+			idToken: func(s *stubIdP) string {
+				claims := s.claims()
+				claims["aud"] = "another-client"
+				return s.sign("key-1", claims)
+			},
+			wantErr: "invalid id token",
+		},
+		{
+			name: "token issuer mismatch",
+			idToken: func(s *stubIdP) string {
+				claims := s.claims()
+				claims["iss"] = "https://idp.example.com"
+				return s.sign("key-1", claims)
+			},
+			wantErr: "invalid id token",
+		},
+		{
+			name: "expired",
+			idToken: func(s *stubIdP) string {
+				claims := s.claims()
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+				return s.sign("key-1", claims)
+			},
+			wantErr: "invalid id token",
+		},
+		{
+			name: "signed with another key id",
+			idToken: func(s *stubIdP) string {
+				return s.sign("key-0", s.claims())
+			},
+			wantErr: "invalid id token",
+		},
+		{
+			name: "unknown key id",
+			idToken: func(s *stubIdP) string {
+				return s.sign("key-9", s.claims())
+			},
+			wantErr: "no signing key",
+		},
+	}
+
+	for _, tt := range tests {
+		idp := newStubIdP(t)
+		idp.issuer = tt.issuer
+		if tt.idToken != nil {
+			idp.idToken = tt.idToken
+		}
+		verifier := testCodeVerifier
+		if tt.verifier != "" {
+			verifier = tt.verifier
+		}
+
+		claims, err := idp.provider().Exchange(context.Background(), testCode, verifier, testNonce)
+		if err == nil {
+			t.Errorf("%s: Exchange accepted the token, claims %v", tt.name, claims)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: error = %q, want it to contain %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"github.com/novianakbar/livechat-be/pkg/utils"
+	"gorm.io/gorm"
+)
+
+type chatAnalyticsRepository struct {
+	db *gorm.DB
+}
+
+func NewChatAnalyticsRepository(db *gorm.DB) domain.ChatAnalyticsRepository {
+	return &chatAnalyticsRepository{db: db}
+}
+
+func (r *chatAnalyticsRepository) Create(ctx context.Context, analytics *domain.ChatAnalytics) error {
+	return r.db.WithContext(ctx).Create(analytics).Error
+}
+
+func (r *chatAnalyticsRepository) GetByDateRange(ctx context.Context, start, end time.Time) ([]*domain.ChatAnalyticsRollup, error) {
+	var analytics []*domain.ChatAnalyticsRollup
+	if err := r.db.WithContext(ctx).
+		Where("date >= ? AND date <= ?", start.Format(utils.DateLayout), end.Format(utils.DateLayout)).
+		Order("date ASC").
+		Find(&analytics).Error; err != nil {
+		return nil, err
+	}
+	return analytics, nil
+}
+
+func (r *chatAnalyticsRepository) GetByAgentAndDateRange(ctx context.Context, agentID uuid.UUID, start, end time.Time) ([]*domain.ChatAnalyticsRollup, error) {
+	var analytics []*domain.ChatAnalyticsRollup
+	if err := r.db.WithContext(ctx).
+		Where("agent_id = ? AND date >= ? AND date <= ?", agentID, start.Format(utils.DateLayout), end.Format(utils.DateLayout)).
+		Order("date ASC").
+		Find(&analytics).Error; err != nil {
+		return nil, err
+	}
+	return analytics, nil
+}
+
+func (r *chatAnalyticsRepository) GetByDepartmentAndDateRange(ctx context.Context, departmentID uuid.UUID, start, end time.Time) ([]*domain.ChatAnalyticsRollup, error) {
+	var analytics []*domain.ChatAnalyticsRollup
+	if err := r.db.WithContext(ctx).
+		Where("department_id = ? AND date >= ? AND date <= ?", departmentID, start.Format(utils.DateLayout), end.Format(utils.DateLayout)).
+		Order("date ASC").
+		Find(&analytics).Error; err != nil {
+		return nil, err
+	}
+	return analytics, nil
+}
+
+// ReplaceDay replaces all rows of the day with the given ones, so groups
+// that no longer exist, e.g. a session that got an agent since the last
+// rollup, do not keep their old row. Rows are derived data, so the old ones
+// are deleted for good instead of piling up soft-deleted every night.
+func (r *chatAnalyticsRepository) ReplaceDay(ctx context.Context, day time.Time, analytics []*domain.ChatAnalyticsRollup) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("date = ?", utils.StartOfDay(day).Format(utils.DateLayout)).Delete(&domain.ChatAnalyticsRollup{}).Error; err != nil {
+			return err
+		}
+
+		if len(analytics) == 0 {
+			return nil
+		}
+		return tx.Create(&analytics).Error
+	})
+}
+
+// AggregateDay computes analytics rows for sessions started on the given day,
+// one row per department and agent combination. The response time is averaged
+// over the sessions with an agent reply only. The rows are not stored.
+func (r *chatAnalyticsRepository) AggregateDay(ctx context.Context, day time.Time) ([]*domain.ChatAnalyticsRollup, error) {
+	start := utils.StartOfDay(day)
+	end := start.AddDate(0, 0, 1)
+
+	var analytics []*domain.ChatAnalyticsRollup
+	if err := r.db.WithContext(ctx).Raw(`
+		SELECT
+			?::date AS date,
+			s.department_id,
+			s.agent_id,
+			COUNT(*) AS total_sessions,
+			COUNT(*) FILTER (WHERE s.status = 'closed') AS completed_sessions,
+			COUNT(r.first_reply_at) AS responded_sessions,
+			COALESCE(AVG(EXTRACT(EPOCH FROM (r.first_reply_at - s.started_at))), 0) AS average_response_time,
+			COALESCE(SUM((
+				SELECT COUNT(*) FROM chat_messages m
+				WHERE m.session_id = s.id AND m.deleted_at = 0
+			)), 0) AS total_messages
+		FROM chat_sessions s
+		LEFT JOIN LATERAL (
+			SELECT MIN(m.created_at) AS first_reply_at FROM chat_messages m
+			WHERE m.session_id = s.id AND m.sender_type = 'agent' AND m.deleted_at = 0
+		) r ON true
+		WHERE s.deleted_at = 0 AND s.started_at >= ? AND s.started_at < ?
+		GROUP BY s.department_id, s.agent_id`, start.Format(utils.DateLayout), start, end).
+		Scan(&analytics).Error; err != nil {
+		return nil, err
+	}
+
+	return analytics, nil
+}
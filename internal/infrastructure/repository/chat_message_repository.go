@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"errors"
+	"html"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -91,14 +93,94 @@ func (r *chatMessageRepository) GetMessagesByDateRange(ctx context.Context, star
 }
 
 // Analytics methods
-func (r *chatMessageRepository) GetTopQuestions(ctx context.Context, limit int) ([]domain.QuestionStats, error) {
-	// This would analyze message content to find common questions
-	// For now, return mock data based on OSS common questions
-	return []domain.QuestionStats{
-		{Question: "Bagaimana cara mengurus NIB?", Count: 234},
-		{Question: "Berapa lama proses penerbitan izin?", Count: 187},
-		{Question: "Dokumen apa saja yang diperlukan?", Count: 156},
-		{Question: "Apakah ada biaya pengurusan?", Count: 142},
-		{Question: "Status permohonan saya bagaimana?", Count: 98},
-	}, nil
+
+// GetTopQuestions clusters the first customer message of each session started
+// in the period by normalized text (lowercase, punctuation and extra spaces
+// removed) and returns the most frequent ones
+func (r *chatMessageRepository) GetTopQuestions(ctx context.Context, start, end time.Time, limit int) ([]domain.QuestionStats, error) {
+	var questions []domain.QuestionStats
+	if err := r.db.WithContext(ctx).Raw(`
+		WITH first_messages AS (
+			SELECT DISTINCT ON (m.session_id) m.message
+			FROM chat_messages m
+			JOIN chat_sessions s ON s.id = m.session_id
+			WHERE m.sender_type = 'customer' AND m.deleted_at = 0 AND s.deleted_at = 0
+				AND s.started_at >= ? AND s.started_at < ?
+			ORDER BY m.session_id, m.created_at ASC
+		), normalized AS (
+			SELECT message,
+				btrim(regexp_replace(regexp_replace(lower(message), '[^[:alnum:][:space:]]', ' ', 'g'), '\s+', ' ', 'g')) AS question_key
+			FROM first_messages
+		)
+		SELECT MIN(message) AS question, COUNT(*) AS count
+		FROM normalized
+		WHERE question_key <> ''
+		GROUP BY question_key
+		ORDER BY count DESC, question ASC
+		LIMIT ?`, start, end, limit).
+		Scan(&questions).Error; err != nil {
+		return nil, err
+	}
+
+	return questions, nil
+}
+
+// Messages are customer text, so ts_headline marks matches with private use
+// characters instead of tags. The snippet is HTML-escaped before they become
+// <mark> tags; the characters are stripped from messages beforehand.
+const (
+	searchMatchStart = "\uE000"
+	searchMatchStop  = "\uE001"
+	// messageSearchHeadline marks up to two fragments of each matching message
+	messageSearchHeadline = "StartSel=" + searchMatchStart + ", StopSel=" + searchMatchStop + ", MaxFragments=2, MaxWords=20, MinWords=5"
+)
+
+var searchMatchTags = strings.NewReplacer(searchMatchStart, "<mark>", searchMatchStop, "</mark>")
+
+// highlightSnippet HTML-escapes a headline and turns its match markers into
+// <mark> tags
+func highlightSnippet(headline string) string {
+	return searchMatchTags.Replace(html.EscapeString(headline))
+}
+
+// Search matches search_vector, which holds both the Indonesian and English
+// stems of each message, against the query parsed with both stemmers
+func (r *chatMessageRepository) Search(ctx context.Context, req *domain.MessageSearchRequest) ([]domain.MessageSearchResult, int, error) {
+	query := r.db.WithContext(ctx).
+		Table("chat_messages m").
+		Joins("JOIN chat_sessions s ON s.id = m.session_id AND s.deleted_at = 0").
+		Joins("CROSS JOIN (SELECT websearch_to_tsquery('indonesian', ?) || websearch_to_tsquery('english', ?) AS query) q", req.Query, req.Query).
+		Where("m.deleted_at = 0 AND m.sender_type <> ? AND m.search_vector @@ q.query", "system")
+
+	if req.DepartmentID != nil {
+		query = query.Where("s.department_id = ?", *req.DepartmentID)
+	}
+
+	// Count and Scan below both start from the filters above
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var results []domain.MessageSearchResult
+	if err := query.
+		Select(`m.id AS message_id, m.session_id, m.sender_type, m.created_at,
+			ts_headline('english', translate(m.message, ?, ''), q.query, ?) AS snippet,
+			ts_rank(m.search_vector, q.query) AS rank,
+			s.topic AS session_topic, s.status AS session_status,
+			COALESCE(s.department_id, '') AS department_id`, searchMatchStart+searchMatchStop, messageSearchHeadline).
+		Order("rank DESC, m.created_at DESC").
+		Offset(req.Offset).
+		Limit(req.Limit).
+		Scan(&results).Error; err != nil {
+		return nil, 0, err
+	}
+
+	for i := range results {
+		results[i].Snippet = highlightSnippet(results[i].Snippet)
+	}
+
+	return results, int(total), nil
 }
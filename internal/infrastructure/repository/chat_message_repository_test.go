@@ -0,0 +1,26 @@
+package repository
+
+import "testing"
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		headline string
+		want     string
+	}{
+		{headline: "", want: ""},
+		{headline: "cara mengurus " + searchMatchStart + "izin" + searchMatchStop + " usaha",
+			want: "cara mengurus <mark>izin</mark> usaha"},
+		{headline: searchMatchStart + "NIB" + searchMatchStop + " & " + searchMatchStart + "NPWP" + searchMatchStop,
+			want: "<mark>NIB</mark> &amp; <mark>NPWP</mark>"},
+		{headline: `<script>alert("x")</script> ` + searchMatchStart + "izin" + searchMatchStop,
+			want: "&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; <mark>izin</mark>"},
+		{headline: "<mark onmouseover='x'>izin</mark>",
+			want: "&lt;mark onmouseover=&#39;x&#39;&gt;izin&lt;/mark&gt;"},
+	}
+
+	for _, tt := range tests {
+		if got := highlightSnippet(tt.headline); got != tt.want {
+			t.Errorf("highlightSnippet(%q) = %q, want %q", tt.headline, got, tt.want)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,6 +13,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// uncategorizedCategory is reported for topics that match no category rule
+const uncategorizedCategory = "Lainnya"
+
 type chatSessionRepository struct {
 	db *gorm.DB
 }
@@ -68,30 +74,42 @@ func (r *chatSessionRepository) GetByAgentID(ctx context.Context, agentID uuid.U
 	return sessions, nil
 }
 
-func (r *chatSessionRepository) GetActiveSessions(ctx context.Context) ([]*domain.ChatSession, error) {
-	var sessions []*domain.ChatSession
-	if err := r.db.WithContext(ctx).
+func (r *chatSessionRepository) GetActiveSessions(ctx context.Context, departmentID *uuid.UUID) ([]*domain.ChatSession, error) {
+	query := r.db.WithContext(ctx).
 		Preload("ChatUser").
 		Preload("Agent").
 		Preload("Department").
 		Preload("Contact").
-		Where("status = ?", "active").
-		Order("created_at DESC").
-		Find(&sessions).Error; err != nil {
+		Where("status = ?", "active")
+
+	if departmentID != nil {
+		query = query.Where("department_id = ?", *departmentID)
+	}
+
+	var sessions []*domain.ChatSession
+	if err := query.Order("created_at DESC").Find(&sessions).Error; err != nil {
 		return nil, err
 	}
 	return sessions, nil
 }
 
-func (r *chatSessionRepository) GetWaitingSessions(ctx context.Context) ([]*domain.ChatSession, error) {
-	var sessions []*domain.ChatSession
-	if err := r.db.WithContext(ctx).
+func (r *chatSessionRepository) GetWaitingSessions(ctx context.Context, departmentID *uuid.UUID) ([]*domain.ChatSession, error) {
+	query := r.db.WithContext(ctx).
 		Preload("ChatUser").
 		Preload("Agent").
 		Preload("Department").
 		Preload("Contact").
-		Where("status = ?", "waiting").
-		Order("created_at ASC").
+		Where("status = ?", "waiting")
+
+	if departmentID != nil {
+		query = query.Where("department_id = ?", *departmentID)
+	}
+
+	// Same order as the Redis waiting queue: priority first, then oldest
+	var sessions []*domain.ChatSession
+	if err := query.
+		Order("CASE priority WHEN 'urgent' THEN 0 WHEN 'high' THEN 1 WHEN 'normal' THEN 2 ELSE 3 END").
+		Order("started_at ASC").
 		Find(&sessions).Error; err != nil {
 		return nil, err
 	}
@@ -113,6 +131,24 @@ func (r *chatSessionRepository) Close(ctx context.Context, sessionID uuid.UUID)
 		}).Error
 }
 
+// AssignIfWaiting gives the session to the agent and activates it, but only
+// while it is still waiting without an agent. It reports whether it did.
+func (r *chatSessionRepository) AssignIfWaiting(ctx context.Context, sessionID uuid.UUID, agentID string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&domain.ChatSession{}).
+		Where("id = ? AND status = ? AND agent_id IS NULL", sessionID, "waiting").
+		Updates(map[string]interface{}{
+			"agent_id":   agentID,
+			"status":     "active",
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
 func (r *chatSessionRepository) GetSessionsByStatus(ctx context.Context, status string) ([]*domain.ChatSession, error) {
 	var sessions []*domain.ChatSession
 	if err := r.db.WithContext(ctx).
@@ -162,43 +198,144 @@ func (r *chatSessionRepository) CountCompletedSince(ctx context.Context, since t
 	return count, nil
 }
 
-func (r *chatSessionRepository) GetAverageResponseTime(ctx context.Context) (float64, error) {
-	// This would calculate average time from session start to first agent response
-	// For now, return a mock value (in seconds)
-	return 180.0, nil // 3 minutes average
+// GetAverageResponseTime returns the average seconds from session start to the
+// first agent message. The date range applies to started_at; sessions without
+// an agent reply are ignored.
+func (r *chatSessionRepository) GetAverageResponseTime(ctx context.Context, filter domain.SessionMetricsFilter) (float64, error) {
+	query := r.db.WithContext(ctx).
+		Model(&domain.ChatSession{}).
+		Select("COALESCE(AVG(EXTRACT(EPOCH FROM (first_response.created_at - chat_sessions.started_at))), 0)").
+		Joins(`JOIN LATERAL (
+			SELECT MIN(m.created_at) AS created_at FROM chat_messages m
+			WHERE m.session_id = chat_sessions.id AND m.sender_type = 'agent' AND m.deleted_at = 0
+		) first_response ON first_response.created_at IS NOT NULL`)
+
+	query = applySessionMetricsFilter(query, filter, "chat_sessions.started_at")
+
+	var average float64
+	if err := query.Scan(&average).Error; err != nil {
+		return 0, err
+	}
+
+	return average, nil
+}
+
+// GetAverageHandleTime returns the average seconds from session start to
+// ended_at for closed sessions. The date range applies to ended_at.
+func (r *chatSessionRepository) GetAverageHandleTime(ctx context.Context, filter domain.SessionMetricsFilter) (float64, error) {
+	query := r.db.WithContext(ctx).
+		Model(&domain.ChatSession{}).
+		Select("COALESCE(AVG(EXTRACT(EPOCH FROM (chat_sessions.ended_at - chat_sessions.started_at))), 0)").
+		Where("chat_sessions.status = ? AND chat_sessions.agent_id IS NOT NULL AND chat_sessions.ended_at IS NOT NULL", "closed")
+
+	query = applySessionMetricsFilter(query, filter, "chat_sessions.ended_at")
+
+	var average float64
+	if err := query.Scan(&average).Error; err != nil {
+		return 0, err
+	}
+
+	return average, nil
 }
 
-func (r *chatSessionRepository) GetOSSCategoriesStats(ctx context.Context) ([]domain.CategoryStats, error) {
-	// This would analyze topics to categorize OSS requests
-	// For now, return mock data
-	return []domain.CategoryStats{
-		{Category: "NIB (Nomor Induk Berusaha)", Count: 345, Percentage: 35},
-		{Category: "Izin Usaha Perdagangan", Count: 289, Percentage: 29},
-		{Category: "Izin Usaha Industri", Count: 198, Percentage: 20},
-		{Category: "Izin Usaha Jasa", Count: 156, Percentage: 16},
-	}, nil
+// applySessionMetricsFilter adds the optional filters, using dateColumn for the date range
+func applySessionMetricsFilter(query *gorm.DB, filter domain.SessionMetricsFilter, dateColumn string) *gorm.DB {
+	if filter.StartDate != nil {
+		query = query.Where(dateColumn+" >= ?", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where(dateColumn+" < ?", *filter.EndDate)
+	}
+	if filter.DepartmentID != nil {
+		query = query.Where("chat_sessions.department_id = ?", *filter.DepartmentID)
+	}
+	if filter.AgentID != nil {
+		query = query.Where("chat_sessions.agent_id = ?", *filter.AgentID)
+	}
+	return query
 }
 
-func (r *chatSessionRepository) GetWithPagination(ctx context.Context, offset, limit int, status string, agentID, departmentID *uuid.UUID) ([]*domain.ChatSession, error) {
+// GetOSSCategoriesStats classifies the topics of sessions started in the
+// period using the keyword rules in topic_category_rules. A topic belongs to
+// the matching rule with the lowest priority; unmatched topics count as "Lainnya".
+func (r *chatSessionRepository) GetOSSCategoriesStats(ctx context.Context, start, end time.Time) ([]domain.CategoryStats, error) {
+	var rows []struct {
+		Category string
+		Count    int
+	}
+	if err := r.db.WithContext(ctx).Raw(`
+		SELECT category, COUNT(*) AS count
+		FROM (
+			SELECT COALESCE((
+				SELECT r.category FROM topic_category_rules r
+				WHERE r.deleted_at = 0 AND POSITION(LOWER(r.keyword) IN LOWER(s.topic)) > 0
+				ORDER BY r.priority ASC, LENGTH(r.keyword) DESC
+				LIMIT 1
+			), ?) AS category
+			FROM chat_sessions s
+			WHERE s.deleted_at = 0 AND s.started_at >= ? AND s.started_at < ?
+		) classified
+		GROUP BY category
+		ORDER BY count DESC, category ASC`, uncategorizedCategory, start, end).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	total := 0
+	for _, row := range rows {
+		total += row.Count
+	}
+
+	stats := make([]domain.CategoryStats, 0, len(rows))
+	for _, row := range rows {
+		stats = append(stats, domain.CategoryStats{
+			Category:   row.Category,
+			Count:      row.Count,
+			Percentage: int(math.Round(float64(row.Count) * 100 / float64(total))),
+		})
+	}
+
+	return stats, nil
+}
+
+// sessionSortColumns whitelists the sort_by values of session lists
+var sessionSortColumns = map[string]string{
+	"created_at": "chat_sessions.created_at",
+	"started_at": "chat_sessions.started_at",
+	"ended_at":   "chat_sessions.ended_at",
+	"updated_at": "chat_sessions.updated_at",
+	"status":     "chat_sessions.status",
+	// Priorities sort by urgency rather than alphabetically
+	"priority": "CASE chat_sessions.priority WHEN 'urgent' THEN 4 WHEN 'high' THEN 3 WHEN 'normal' THEN 2 ELSE 1 END",
+}
+
+func (r *chatSessionRepository) GetWithPagination(ctx context.Context, offset, limit int, filter *domain.SessionListRequest) ([]*domain.ChatSession, error) {
+	sortColumn := sessionSortColumns["created_at"]
+	if filter.SortBy != "" {
+		column, ok := sessionSortColumns[filter.SortBy]
+		if !ok {
+			return nil, errors.New("unsupported sort field")
+		}
+		sortColumn = column
+	}
+
+	sortDir := "DESC"
+	if strings.EqualFold(filter.SortDir, "asc") {
+		sortDir = "ASC"
+	}
+
 	query := r.db.WithContext(ctx).
 		Preload("ChatUser").
 		Preload("Agent").
 		Preload("Department").
 		Preload("Contact")
 
-	if status != "" {
-		query = query.Where("status = ?", status)
-	}
-	if agentID != nil {
-		query = query.Where("agent_id = ?", *agentID)
-	}
-	if departmentID != nil {
-		query = query.Where("department_id = ?", *departmentID)
-	}
+	query = applySessionListFilter(query, filter)
 
 	var sessions []*domain.ChatSession
 	if err := query.
-		Order("created_at DESC").
+		// The ID breaks ties so pages do not overlap
+		Order(sortColumn + " " + sortDir + " NULLS LAST, chat_sessions.id " + sortDir).
 		Offset(offset).
 		Limit(limit).
 		Find(&sessions).Error; err != nil {
@@ -208,25 +345,201 @@ func (r *chatSessionRepository) GetWithPagination(ctx context.Context, offset, l
 	return sessions, nil
 }
 
+func (r *chatSessionRepository) CountWithFilter(ctx context.Context, filter *domain.SessionListRequest) (int, error) {
+	query := applySessionListFilter(r.db.WithContext(ctx).Model(&domain.ChatSession{}), filter)
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return int(count), nil
+}
+
 func (r *chatSessionRepository) Count(ctx context.Context, status string, agentID, departmentID *uuid.UUID) (int, error) {
+	return r.CountWithFilter(ctx, &domain.SessionListRequest{
+		Status:       status,
+		AgentID:      agentID,
+		DepartmentID: departmentID,
+	})
+}
+
+// applySessionListFilter adds the filters and free-text search of a session list
+func applySessionListFilter(query *gorm.DB, filter *domain.SessionListRequest) *gorm.DB {
+	if filter.Status != "" {
+		query = query.Where("chat_sessions.status = ?", filter.Status)
+	}
+	if filter.AgentID != nil {
+		query = query.Where("chat_sessions.agent_id = ?", *filter.AgentID)
+	}
+	if filter.DepartmentID != nil {
+		query = query.Where("chat_sessions.department_id = ?", *filter.DepartmentID)
+	}
+	if filter.CustomerID != nil {
+		query = query.Where("chat_sessions.chat_user_id = ?", *filter.CustomerID)
+	}
+	if filter.Priority != "" {
+		query = query.Where("chat_sessions.priority = ?", filter.Priority)
+	}
+	if filter.DateFrom != nil {
+		query = query.Where("chat_sessions.started_at >= ?", *filter.DateFrom)
+	}
+	if filter.DateTo != nil {
+		query = query.Where("chat_sessions.started_at < ?", *filter.DateTo)
+	}
+	if filter.TagID != nil {
+		query = query.Where(`EXISTS (
+			SELECT 1 FROM chat_session_tags st
+			WHERE st.session_id = chat_sessions.id AND st.tag_id = ? AND st.deleted_at = 0
+		)`, *filter.TagID)
+	}
+
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := "%" + escapeLike(search) + "%"
+		query = query.Where(`(
+			chat_sessions.topic ILIKE @pattern
+			OR EXISTS (
+				SELECT 1 FROM chat_session_contacts sc
+				WHERE sc.session_id = chat_sessions.id AND sc.deleted_at = 0
+				AND (sc.contact_name ILIKE @pattern OR sc.contact_email ILIKE @pattern)
+			)
+			OR EXISTS (
+				SELECT 1 FROM chat_messages m
+				WHERE m.session_id = chat_sessions.id AND m.deleted_at = 0 AND m.message ILIKE @pattern
+			)
+		)`, sql.Named("pattern", pattern))
+	}
+
+	return query
+}
+
+// escapeLike escapes the LIKE wildcards in user input
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// CountOpenByAgents counts sessions that are not closed yet, grouped by agent
+func (r *chatSessionRepository) CountOpenByAgents(ctx context.Context, agentIDs []string) (map[string]int, error) {
+	counts := make(map[string]int, len(agentIDs))
+	if len(agentIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		AgentID string
+		Total   int
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&domain.ChatSession{}).
+		Select("agent_id, COUNT(*) AS total").
+		Where("agent_id IN ? AND status <> ?", agentIDs, "closed").
+		Group("agent_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.AgentID] = row.Total
+	}
+
+	return counts, nil
+}
+
+// GetAgentSessionStats counts sessions started in the period, their messages
+// and timings, grouped by the assigned agent
+func (r *chatSessionRepository) GetAgentSessionStats(ctx context.Context, start, end time.Time) (map[string]domain.AgentSessionStats, error) {
+	var rows []domain.AgentSessionStats
+	if err := r.db.WithContext(ctx).
+		Model(&domain.ChatSession{}).
+		Select(`agent_id,
+			COUNT(*) AS total_sessions,
+			COUNT(*) FILTER (WHERE status = 'closed') AS completed_sessions,
+			COALESCE(SUM((
+				SELECT COUNT(*) FROM chat_messages m
+				WHERE m.session_id = chat_sessions.id AND m.deleted_at = 0
+			)), 0) AS total_messages,
+			COALESCE(AVG(EXTRACT(EPOCH FROM ((
+				SELECT MIN(m.created_at) FROM chat_messages m
+				WHERE m.session_id = chat_sessions.id AND m.sender_type = 'agent' AND m.deleted_at = 0
+			) - started_at))), 0) AS average_response_time,
+			COALESCE(AVG(EXTRACT(EPOCH FROM (ended_at - started_at))) FILTER (WHERE status = 'closed'), 0) AS average_handle_time`).
+		Where("agent_id IS NOT NULL AND started_at >= ? AND started_at < ?", start, end).
+		Group("agent_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]domain.AgentSessionStats, len(rows))
+	for _, row := range rows {
+		stats[row.AgentID] = row
+	}
+	return stats, nil
+}
+
+// CountGroupedBy counts sessions started in the filter's date range grouped
+// by status, priority or tag name. Sessions with several tags count once per
+// tag; untagged sessions are left out of the tag counts.
+func (r *chatSessionRepository) CountGroupedBy(ctx context.Context, column string, filter domain.SessionMetricsFilter) (map[string]int, error) {
 	query := r.db.WithContext(ctx).Model(&domain.ChatSession{})
 
-	if status != "" {
-		query = query.Where("status = ?", status)
+	switch column {
+	case "status", "priority":
+		query = query.
+			Select("chat_sessions." + column + " AS value, COUNT(*) AS total").
+			Group("chat_sessions." + column)
+	case "tag":
+		query = query.
+			Select("chat_tags.name AS value, COUNT(*) AS total").
+			Joins("JOIN chat_session_tags ON chat_session_tags.session_id = chat_sessions.id AND chat_session_tags.deleted_at = 0").
+			Joins("JOIN chat_tags ON chat_tags.id = chat_session_tags.tag_id AND chat_tags.deleted_at = 0").
+			Group("chat_tags.name")
+	default:
+		return nil, errors.New("unsupported group column")
 	}
-	if agentID != nil {
-		query = query.Where("agent_id = ?", *agentID)
+
+	query = applySessionMetricsFilter(query, filter, "chat_sessions.started_at")
+
+	var rows []struct {
+		Value string
+		Total int
 	}
-	if departmentID != nil {
-		query = query.Where("department_id = ?", *departmentID)
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
 	}
 
-	var count int64
-	if err := query.Count(&count).Error; err != nil {
-		return 0, err
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Value] = row.Total
 	}
+	return counts, nil
+}
 
-	return int(count), nil
+// GetIdleSessions returns sessions in the given status whose last non-system
+// message (or start, if there is none) is older than idleSince
+func (r *chatSessionRepository) GetIdleSessions(ctx context.Context, status string, idleSince time.Time) ([]*domain.IdleSession, error) {
+	var sessions []*domain.IdleSession
+	if err := r.db.WithContext(ctx).Raw(`
+		SELECT id AS session_id, status, last_activity_at, warned_at
+		FROM (
+			SELECT s.id, s.status,
+				COALESCE((
+					SELECT MAX(m.created_at) FROM chat_messages m
+					WHERE m.session_id = s.id AND m.sender_type <> 'system' AND m.deleted_at = 0
+				), s.started_at) AS last_activity_at,
+				(
+					SELECT MAX(l.created_at) FROM chat_logs l
+					WHERE l.session_id = s.id AND l.action = 'idle_warning' AND l.deleted_at = 0
+				) AS warned_at
+			FROM chat_sessions s
+			WHERE s.status = ? AND s.deleted_at = 0
+		) activity
+		WHERE last_activity_at < ?
+		ORDER BY last_activity_at ASC`, status, idleSince).
+		Scan(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
 }
 
 func (r *chatSessionRepository) GetSessionsWithMessages(ctx context.Context, chatUserID uuid.UUID, limit, offset int) ([]*domain.ChatSession, error) {
@@ -249,6 +562,16 @@ func (r *chatSessionRepository) GetSessionsWithMessages(ctx context.Context, cha
 	return sessions, nil
 }
 
+func (r *chatSessionRepository) CountByChatUserID(ctx context.Context, chatUserID uuid.UUID) (int, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&domain.ChatSession{}).
+		Where("chat_user_id = ?", chatUserID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
 func (r *chatSessionRepository) GetSessionHistory(ctx context.Context, chatUserID uuid.UUID, limit, offset int) ([]*domain.ChatSession, error) {
 	var sessions []*domain.ChatSession
 	if err := r.db.WithContext(ctx).
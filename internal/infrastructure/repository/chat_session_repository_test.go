@@ -0,0 +1,26 @@
+package repository
+
+import "testing"
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "", want: ""},
+		{value: "izin usaha", want: "izin usaha"},
+		{value: "100%", want: `100\%`},
+		{value: "nib_123", want: `nib\_123`},
+		{value: `C:\path`, want: `C:\\path`},
+		{value: `%_\`, want: `\%\_\\`},
+		// The escape character is escaped first, so an escaped wildcard in the
+		// input stays a literal backslash followed by a literal wildcard
+		{value: `\%`, want: `\\\%`},
+	}
+
+	for _, tt := range tests {
+		if got := escapeLike(tt.value); got != tt.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
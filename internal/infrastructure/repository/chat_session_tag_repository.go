@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"gorm.io/gorm"
+)
+
+type chatSessionTagRepository struct {
+	db *gorm.DB
+}
+
+func NewChatSessionTagRepository(db *gorm.DB) domain.ChatSessionTagRepository {
+	return &chatSessionTagRepository{db: db}
+}
+
+func (r *chatSessionTagRepository) Create(ctx context.Context, sessionTag *domain.ChatSessionTag) error {
+	return r.db.WithContext(ctx).Create(sessionTag).Error
+}
+
+func (r *chatSessionTagRepository) GetBySessionID(ctx context.Context, sessionID uuid.UUID) ([]*domain.ChatSessionTag, error) {
+	return r.GetBySessionIDs(ctx, []string{sessionID.String()})
+}
+
+func (r *chatSessionTagRepository) GetBySessionIDs(ctx context.Context, sessionIDs []string) ([]*domain.ChatSessionTag, error) {
+	var sessionTags []*domain.ChatSessionTag
+	if len(sessionIDs) == 0 {
+		return sessionTags, nil
+	}
+
+	if err := r.db.WithContext(ctx).
+		Preload("Tag").
+		Where("session_id IN ?", sessionIDs).
+		Order("created_at ASC").
+		Find(&sessionTags).Error; err != nil {
+		return nil, err
+	}
+	return sessionTags, nil
+}
+
+func (r *chatSessionTagRepository) Delete(ctx context.Context, sessionID, tagID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Delete(&domain.ChatSessionTag{}, "session_id = ? AND tag_id = ?", sessionID, tagID).Error
+}
+
+func (r *chatSessionTagRepository) DeleteBySessionID(ctx context.Context, sessionID uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&domain.ChatSessionTag{}, "session_id = ?", sessionID).Error
+}
+
+func (r *chatSessionTagRepository) DeleteByTagID(ctx context.Context, tagID uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&domain.ChatSessionTag{}, "tag_id = ?", tagID).Error
+}
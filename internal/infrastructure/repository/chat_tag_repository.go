@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"gorm.io/gorm"
+)
+
+type chatTagRepository struct {
+	db *gorm.DB
+}
+
+func NewChatTagRepository(db *gorm.DB) domain.ChatTagRepository {
+	return &chatTagRepository{db: db}
+}
+
+func (r *chatTagRepository) Create(ctx context.Context, tag *domain.ChatTag) error {
+	return r.db.WithContext(ctx).Create(tag).Error
+}
+
+func (r *chatTagRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.ChatTag, error) {
+	var tag domain.ChatTag
+	if err := r.db.WithContext(ctx).First(&tag, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *chatTagRepository) GetAll(ctx context.Context) ([]*domain.ChatTag, error) {
+	var tags []*domain.ChatTag
+	if err := r.db.WithContext(ctx).
+		Order("name ASC").
+		Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *chatTagRepository) Update(ctx context.Context, tag *domain.ChatTag) error {
+	return r.db.WithContext(ctx).Save(tag).Error
+}
+
+func (r *chatTagRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&domain.ChatTag{}, "id = ?", id).Error
+}
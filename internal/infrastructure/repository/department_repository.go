@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"gorm.io/gorm"
+)
+
+type departmentRepository struct {
+	db *gorm.DB
+}
+
+func NewDepartmentRepository(db *gorm.DB) domain.DepartmentRepository {
+	return &departmentRepository{db: db}
+}
+
+func (r *departmentRepository) Create(ctx context.Context, department *domain.Department) error {
+	return r.db.WithContext(ctx).Create(department).Error
+}
+
+func (r *departmentRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Department, error) {
+	var department domain.Department
+	if err := r.db.WithContext(ctx).First(&department, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &department, nil
+}
+
+func (r *departmentRepository) GetAll(ctx context.Context) ([]*domain.Department, error) {
+	var departments []*domain.Department
+	if err := r.db.WithContext(ctx).
+		Order("name ASC").
+		Find(&departments).Error; err != nil {
+		return nil, err
+	}
+	return departments, nil
+}
+
+func (r *departmentRepository) Update(ctx context.Context, department *domain.Department) error {
+	return r.db.WithContext(ctx).Save(department).Error
+}
+
+func (r *departmentRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&domain.Department{}, "id = ?", id).Error
+}
+
+func (r *departmentRepository) CountUsers(ctx context.Context, departmentIDs []string, role string) (map[string]int, error) {
+	counts := make(map[string]int, len(departmentIDs))
+	if len(departmentIDs) == 0 {
+		return counts, nil
+	}
+
+	query := r.db.WithContext(ctx).
+		Model(&domain.User{}).
+		Select("department_id, COUNT(*) AS total").
+		Where("department_id IN ?", departmentIDs)
+	if role != "" {
+		query = query.Where("role = ?", role)
+	}
+
+	var rows []struct {
+		DepartmentID string
+		Total        int
+	}
+	if err := query.Group("department_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.DepartmentID] = row.Total
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	loginFailuresPrefix = "auth:login_failures:"
+	loginLockPrefix     = "auth:login_lock:"
+)
+
+// LoginAttemptRepository counts failed logins and holds temporary lockouts in
+// Redis. Keys are caller-defined, e.g. "email:<address>" or "ip:<address>".
+type LoginAttemptRepository struct {
+	redisClient *redis.Client
+}
+
+func NewLoginAttemptRepository(redisClient *redis.Client) *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		redisClient: redisClient,
+	}
+}
+
+// RecordFailure counts a failed attempt and returns the number of failures
+// within the window. The window restarts with every failure.
+func (r *LoginAttemptRepository) RecordFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	pipe := r.redisClient.TxPipeline()
+	incr := pipe.Incr(ctx, loginFailuresPrefix+key)
+	pipe.Expire(ctx, loginFailuresPrefix+key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// Lock blocks logins for the key for the given duration
+func (r *LoginAttemptRepository) Lock(ctx context.Context, key string, duration time.Duration) error {
+	return r.redisClient.Set(ctx, loginLockPrefix+key, 1, duration).Err()
+}
+
+// LockedFor returns how long the key is still locked, zero when it is not
+func (r *LoginAttemptRepository) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.redisClient.PTTL(ctx, loginLockPrefix+key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// PTTL reports -2 for missing keys and -1 for keys without expiry
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// Reset clears the failure count and any lockout of the key
+func (r *LoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	return r.redisClient.Del(ctx, loginFailuresPrefix+key, loginLockPrefix+key).Err()
+}
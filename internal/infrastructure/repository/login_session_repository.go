@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	loginSessionPrefix      = "auth:session:"
+	userLoginSessionsPrefix = "auth:sessions:user:"
+)
+
+// touchScript updates last-seen only for sessions that still exist, so a late
+// request cannot bring back a terminated or expired session without a TTL
+var touchScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	redis.call('HSET', KEYS[1], 'last_seen', ARGV[1])
+	return 1
+end
+return 0
+`)
+
+// LoginSessionRepository keeps the signed-in devices of users in Redis. The
+// session ID is the refresh token family ID of the login.
+type LoginSessionRepository struct {
+	redisClient *redis.Client
+}
+
+func NewLoginSessionRepository(redisClient *redis.Client) *LoginSessionRepository {
+	return &LoginSessionRepository{
+		redisClient: redisClient,
+	}
+}
+
+// Create registers a session that expires after ttl unless extended
+func (r *LoginSessionRepository) Create(ctx context.Context, session *domain.LoginSession, ttl time.Duration) error {
+	key := loginSessionPrefix + session.SessionID
+	userKey := userLoginSessionsPrefix + session.UserID
+
+	pipe := r.redisClient.TxPipeline()
+	pipe.HSet(ctx, key,
+		"user_id", session.UserID,
+		"client_ip", session.ClientIP,
+		"user_agent", session.UserAgent,
+		"login_time", session.LoginTime.UnixMilli(),
+		"last_seen", session.LastSeen.UnixMilli(),
+	)
+	pipe.Expire(ctx, key, ttl)
+	pipe.SAdd(ctx, userKey, session.SessionID)
+	// The index only needs to outlive the newest session of the user
+	pipe.Expire(ctx, userKey, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Touch records activity on a session. Unknown sessions are ignored.
+func (r *LoginSessionRepository) Touch(ctx context.Context, sessionID string, at time.Time) error {
+	return touchScript.Run(ctx, r.redisClient, []string{loginSessionPrefix + sessionID}, at.UnixMilli()).Err()
+}
+
+// Extend pushes back the expiry of a session, e.g. after its refresh token rotated
+func (r *LoginSessionRepository) Extend(ctx context.Context, userID, sessionID string, ttl time.Duration) error {
+	pipe := r.redisClient.TxPipeline()
+	pipe.Expire(ctx, loginSessionPrefix+sessionID, ttl)
+	pipe.Expire(ctx, userLoginSessionsPrefix+userID, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Get returns a session, or nil when it does not exist (anymore)
+func (r *LoginSessionRepository) Get(ctx context.Context, sessionID string) (*domain.LoginSession, error) {
+	values, err := r.redisClient.HGetAll(ctx, loginSessionPrefix+sessionID).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	return parseLoginSession(sessionID, values), nil
+}
+
+// GetByUser returns the live sessions of a user and drops expired ones from
+// the per-user index
+func (r *LoginSessionRepository) GetByUser(ctx context.Context, userID string) ([]*domain.LoginSession, error) {
+	userKey := userLoginSessionsPrefix + userID
+
+	sessionIDs, err := r.redisClient.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sessionIDs) == 0 {
+		return []*domain.LoginSession{}, nil
+	}
+
+	pipe := r.redisClient.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(sessionIDs))
+	for i, sessionID := range sessionIDs {
+		cmds[i] = pipe.HGetAll(ctx, loginSessionPrefix+sessionID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*domain.LoginSession, 0, len(sessionIDs))
+	var expired []interface{}
+	for i, cmd := range cmds {
+		values := cmd.Val()
+		if len(values) == 0 {
+			expired = append(expired, sessionIDs[i])
+			continue
+		}
+		sessions = append(sessions, parseLoginSession(sessionIDs[i], values))
+	}
+
+	if len(expired) > 0 {
+		r.redisClient.SRem(ctx, userKey, expired...)
+	}
+
+	return sessions, nil
+}
+
+// Delete removes a session of the user
+func (r *LoginSessionRepository) Delete(ctx context.Context, userID, sessionID string) error {
+	pipe := r.redisClient.TxPipeline()
+	pipe.Del(ctx, loginSessionPrefix+sessionID)
+	pipe.SRem(ctx, userLoginSessionsPrefix+userID, sessionID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func parseLoginSession(sessionID string, values map[string]string) *domain.LoginSession {
+	loginTime, _ := strconv.ParseInt(values["login_time"], 10, 64)
+	lastSeen, _ := strconv.ParseInt(values["last_seen"], 10, 64)
+
+	return &domain.LoginSession{
+		SessionID: sessionID,
+		UserID:    values["user_id"],
+		ClientIP:  values["client_ip"],
+		UserAgent: values["user_agent"],
+		LoginTime: time.UnixMilli(loginTime),
+		LastSeen:  time.UnixMilli(lastSeen),
+	}
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type mfaPolicyRepository struct {
+	db *gorm.DB
+}
+
+func NewMFAPolicyRepository(db *gorm.DB) domain.MFAPolicyRepository {
+	return &mfaPolicyRepository{db: db}
+}
+
+func (r *mfaPolicyRepository) GetAll(ctx context.Context) ([]*domain.MFAPolicy, error) {
+	var policies []*domain.MFAPolicy
+	if err := r.db.WithContext(ctx).
+		Order("role ASC").
+		Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// IsRequired reports whether the role must use MFA. Roles without a policy
+// row do not.
+func (r *mfaPolicyRepository) IsRequired(ctx context.Context, role string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&domain.MFAPolicy{}).
+		Where("role = ? AND required = ?", role, true).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *mfaPolicyRepository) SetRequired(ctx context.Context, role string, required bool) error {
+	policy := &domain.MFAPolicy{
+		Role:      role,
+		Required:  required,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "role"}},
+			DoUpdates: clause.AssignmentColumns([]string{"required", "updated_at"}),
+		}).
+		Create(policy).Error
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+const oidcStatePrefix = "auth:oidc_state:"
+
+// OIDCStateRepository holds the PKCE verifier and nonce of SSO logins in
+// progress. Each state can be consumed once.
+type OIDCStateRepository struct {
+	redisClient *redis.Client
+}
+
+func NewOIDCStateRepository(redisClient *redis.Client) *OIDCStateRepository {
+	return &OIDCStateRepository{
+		redisClient: redisClient,
+	}
+}
+
+func (r *OIDCStateRepository) Save(ctx context.Context, state string, authState *domain.OIDCAuthState, ttl time.Duration) error {
+	data, err := json.Marshal(authState)
+	if err != nil {
+		return err
+	}
+	return r.redisClient.Set(ctx, oidcStatePrefix+state, data, ttl).Err()
+}
+
+// Consume returns and deletes the state, or nil when it is unknown or expired
+func (r *OIDCStateRepository) Consume(ctx context.Context, state string) (*domain.OIDCAuthState, error) {
+	data, err := r.redisClient.GetDel(ctx, oidcStatePrefix+state).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var authState domain.OIDCAuthState
+	if err := json.Unmarshal(data, &authState); err != nil {
+		return nil, err
+	}
+	return &authState, nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"gorm.io/gorm"
+)
+
+type permissionRepository struct {
+	db *gorm.DB
+}
+
+func NewPermissionRepository(db *gorm.DB) domain.PermissionRepository {
+	return &permissionRepository{db: db}
+}
+
+func (r *permissionRepository) GetAll(ctx context.Context) ([]*domain.Permission, error) {
+	var permissions []*domain.Permission
+	if err := r.db.WithContext(ctx).
+		Order("name ASC").
+		Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r *permissionRepository) GetRolePermissions(ctx context.Context) ([]*domain.RolePermission, error) {
+	var rolePermissions []*domain.RolePermission
+	if err := r.db.WithContext(ctx).
+		Order("role ASC, permission ASC").
+		Find(&rolePermissions).Error; err != nil {
+		return nil, err
+	}
+	return rolePermissions, nil
+}
+
+// SetRolePermissions replaces all permissions of the role in one transaction
+func (r *permissionRepository) SetRolePermissions(ctx context.Context, role string, permissions []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role = ?", role).Delete(&domain.RolePermission{}).Error; err != nil {
+			return err
+		}
+
+		if len(permissions) == 0 {
+			return nil
+		}
+
+		rolePermissions := make([]*domain.RolePermission, 0, len(permissions))
+		for _, permission := range permissions {
+			rolePermissions = append(rolePermissions, &domain.RolePermission{
+				Role:       role,
+				Permission: permission,
+				CreatedAt:  time.Now(),
+			})
+		}
+		return tx.Create(&rolePermissions).Error
+	})
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const refreshFamilyPrefix = "token:family:"
+
+// rotateScript swaps the current token of a family only if the presented
+// token is the current one, so two concurrent refreshes cannot both succeed.
+// KEYS[1] family key; ARGV[1] presented jti, ARGV[2] next jti, ARGV[3] TTL in ms.
+// Returns 0 when rotated, 1 when the token was already used, 2 when the
+// family is revoked or unknown.
+var rotateScript = redis.NewScript(`
+local family = redis.call('HMGET', KEYS[1], 'current', 'revoked')
+if not family[1] or family[2] == '1' then
+	return 2
+end
+if family[1] ~= ARGV[1] then
+	return 1
+end
+redis.call('HSET', KEYS[1], 'current', ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return 0
+`)
+
+// RefreshTokenFamilyRepository tracks refresh token families in Redis. A
+// family starts at login and holds the single refresh token that may still be
+// exchanged; every refresh rotates it.
+type RefreshTokenFamilyRepository struct {
+	redisClient *redis.Client
+}
+
+func NewRefreshTokenFamilyRepository(redisClient *redis.Client) *RefreshTokenFamilyRepository {
+	return &RefreshTokenFamilyRepository{
+		redisClient: redisClient,
+	}
+}
+
+// Create starts a family whose current refresh token is the given jti
+func (r *RefreshTokenFamilyRepository) Create(ctx context.Context, familyID, userID, jti string, ttl time.Duration) error {
+	key := refreshFamilyPrefix + familyID
+
+	pipe := r.redisClient.TxPipeline()
+	pipe.HSet(ctx, key, "user_id", userID, "current", jti, "revoked", "0")
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Rotate replaces the current refresh token of the family with nextJTI when
+// jti is the current one, and extends the family to the new token's lifetime.
+// reused is set when jti belongs to the family but was rotated already.
+func (r *RefreshTokenFamilyRepository) Rotate(ctx context.Context, familyID, jti, nextJTI string, ttl time.Duration) (rotated bool, reused bool, err error) {
+	result, err := rotateScript.Run(ctx, r.redisClient,
+		[]string{refreshFamilyPrefix + familyID},
+		jti, nextJTI, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, false, err
+	}
+	return result == 0, result == 1, nil
+}
+
+// Revoke marks the family revoked. The marker is kept for ttl so replayed
+// tokens and access tokens of the family keep being rejected.
+func (r *RefreshTokenFamilyRepository) Revoke(ctx context.Context, familyID string, ttl time.Duration) error {
+	key := refreshFamilyPrefix + familyID
+
+	pipe := r.redisClient.TxPipeline()
+	pipe.HSet(ctx, key, "revoked", "1")
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// IsRevoked reports whether the family was revoked. Unknown families are not
+// considered revoked, so tokens issued before families existed keep working.
+func (r *RefreshTokenFamilyRepository) IsRevoked(ctx context.Context, familyID string) (bool, error) {
+	if familyID == "" {
+		return false, nil
+	}
+
+	revoked, err := r.redisClient.HGet(ctx, refreshFamilyPrefix+familyID, "revoked").Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return revoked == "1", nil
+}
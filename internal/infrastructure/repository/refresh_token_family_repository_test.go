@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestFamilyRepository connects to the Redis at TEST_REDIS_ADDR. The rotate
+// script needs a real Lua runtime, so the test is skipped without one.
+func newTestFamilyRepository(t *testing.T) (*RefreshTokenFamilyRepository, string) {
+	t.Helper()
+
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR not set")
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("redis ping: %v", err)
+	}
+
+	familyID := uuid.NewString()
+	t.Cleanup(func() {
+		client.Del(context.Background(), refreshFamilyPrefix+familyID)
+		client.Close()
+	})
+
+	return NewRefreshTokenFamilyRepository(client), familyID
+}
+
+func TestRefreshTokenFamilyRotate(t *testing.T) {
+	repo, familyID := newTestFamilyRepository(t)
+	ctx := context.Background()
+	ttl := time.Minute
+
+	if err := repo.Create(ctx, familyID, "user-1", "jti-1", ttl); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		jti         string
+		nextJTI     string
+		wantRotated bool
+		wantReused  bool
+	}{
+		{name: "current token rotates", jti: "jti-1", nextJTI: "jti-2", wantRotated: true},
+		{name: "rotated token is reused", jti: "jti-1", nextJTI: "jti-3", wantReused: true},
+		{name: "new current token rotates", jti: "jti-2", nextJTI: "jti-3", wantRotated: true},
+	}
+
+	for _, tt := range tests {
+		rotated, reused, err := repo.Rotate(ctx, familyID, tt.jti, tt.nextJTI, ttl)
+		if err != nil {
+			t.Fatalf("%s: Rotate: %v", tt.name, err)
+		}
+		if rotated != tt.wantRotated || reused != tt.wantReused {
+			t.Errorf("%s: got rotated=%v reused=%v, want rotated=%v reused=%v",
+				tt.name, rotated, reused, tt.wantRotated, tt.wantReused)
+		}
+	}
+}
+
+func TestRefreshTokenFamilyRotateRevoked(t *testing.T) {
+	repo, familyID := newTestFamilyRepository(t)
+	ctx := context.Background()
+	ttl := time.Minute
+
+	// Unknown family
+	rotated, reused, err := repo.Rotate(ctx, familyID, "jti-1", "jti-2", ttl)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if rotated || reused {
+		t.Errorf("unknown family: got rotated=%v reused=%v, want both false", rotated, reused)
+	}
+
+	if err := repo.Create(ctx, familyID, "user-1", "jti-1", ttl); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := repo.Revoke(ctx, familyID, ttl); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+
+	rotated, reused, err = repo.Rotate(ctx, familyID, "jti-1", "jti-2", ttl)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if rotated || reused {
+		t.Errorf("revoked family: got rotated=%v reused=%v, want both false", rotated, reused)
+	}
+
+	revoked, err := repo.IsRevoked(ctx, familyID)
+	if err != nil {
+		t.Fatalf("IsRevoked: %v", err)
+	}
+	if !revoked {
+		t.Error("family should stay revoked")
+	}
+}
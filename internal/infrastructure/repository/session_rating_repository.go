@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type sessionRatingRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRatingRepository(db *gorm.DB) domain.SessionRatingRepository {
+	return &sessionRatingRepository{db: db}
+}
+
+// Create stores the rating unless the session already has one. It reports
+// false instead of failing on the unique index when another rating won a race.
+func (r *sessionRatingRepository) Create(ctx context.Context, rating *domain.SessionRating) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "session_id"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Eq{Column: clause.Column{Name: "deleted_at"}, Value: 0}}},
+			DoNothing:   true,
+		}).
+		Create(rating)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *sessionRatingRepository) GetBySessionID(ctx context.Context, sessionID uuid.UUID) (*domain.SessionRating, error) {
+	var rating domain.SessionRating
+	if err := r.db.WithContext(ctx).
+		Where("session_id = ?", sessionID).
+		First(&rating).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rating, nil
+}
+
+// GetSummary returns the average rating and number of ratings given in the period
+func (r *sessionRatingRepository) GetSummary(ctx context.Context, start, end time.Time) (*domain.RatingSummary, error) {
+	var summary domain.RatingSummary
+	if err := r.db.WithContext(ctx).
+		Model(&domain.SessionRating{}).
+		Select("COALESCE(AVG(rating), 0) AS average_rating, COUNT(*) AS total_ratings").
+		Where("created_at >= ? AND created_at < ?", start, end).
+		Scan(&summary).Error; err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// GetSummaryByAgent returns rating summaries keyed by agent ID. Ratings the
+// agent relayed for their own session are left out.
+func (r *sessionRatingRepository) GetSummaryByAgent(ctx context.Context, start, end time.Time) (map[string]domain.RatingSummary, error) {
+	var rows []struct {
+		AgentID       string
+		AverageRating float64
+		TotalRatings  int
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&domain.SessionRating{}).
+		Select("agent_id, AVG(rating) AS average_rating, COUNT(*) AS total_ratings").
+		Where("agent_id IS NOT NULL AND (relayed_by IS NULL OR relayed_by <> agent_id) AND created_at >= ? AND created_at < ?", start, end).
+		Group("agent_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	summaries := make(map[string]domain.RatingSummary, len(rows))
+	for _, row := range rows {
+		summaries[row.AgentID] = domain.RatingSummary{
+			AverageRating: row.AverageRating,
+			TotalRatings:  row.TotalRatings,
+		}
+	}
+	return summaries, nil
+}
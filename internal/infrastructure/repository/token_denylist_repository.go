@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	revokedTokenPrefix      = "token:revoked:"
+	revokedUserTokensPrefix = "token:revoked_before:"
+
+	// Unix seconds stay below this until the year 5138, Unix milliseconds
+	// passed it in 2001
+	legacyRevocationMillisThreshold = 1e12
+)
+
+// TokenDenylistRepository keeps revoked tokens in Redis until they would have
+// expired anyway, so the denylist never outgrows the set of live tokens
+type TokenDenylistRepository struct {
+	redisClient *redis.Client
+}
+
+func NewTokenDenylistRepository(redisClient *redis.Client) *TokenDenylistRepository {
+	return &TokenDenylistRepository{
+		redisClient: redisClient,
+	}
+}
+
+// Revoke denies a single token by its jti for the rest of its lifetime.
+// Tokens that are already expired are not stored.
+func (r *TokenDenylistRepository) Revoke(ctx context.Context, jti string, ttl time.Duration) error {
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	return r.redisClient.Set(ctx, revokedTokenPrefix+jti, 1, ttl).Err()
+}
+
+// IsRevoked reports whether the token with the given jti was revoked
+func (r *TokenDenylistRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	if jti == "" {
+		return false, nil
+	}
+
+	count, err := r.redisClient.Exists(ctx, revokedTokenPrefix+jti).Result()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// RevokeAllForUser denies every token of the user issued at or before the
+// given time. The time is stored in milliseconds so a login right after the
+// revocation is not caught by it. The marker lives as long as the longest
+// token lifetime.
+func (r *TokenDenylistRepository) RevokeAllForUser(ctx context.Context, userID string, at time.Time, ttl time.Duration) error {
+	return r.redisClient.Set(ctx, revokedUserTokensPrefix+userID, at.UnixMilli(), ttl).Err()
+}
+
+// RevokedBefore returns the time up to which all tokens of the user are
+// revoked, or a zero time when there is no such revocation
+func (r *TokenDenylistRepository) RevokedBefore(ctx context.Context, userID string) (time.Time, error) {
+	value, err := r.redisClient.Get(ctx, revokedUserTokensPrefix+userID).Result()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Markers written before the switch to milliseconds hold Unix seconds
+	if millis < legacyRevocationMillisThreshold {
+		return time.Unix(millis, 0), nil
+	}
+	return time.UnixMilli(millis), nil
+}
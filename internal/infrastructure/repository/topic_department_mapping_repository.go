@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"gorm.io/gorm"
+)
+
+type topicDepartmentMappingRepository struct {
+	db *gorm.DB
+}
+
+func NewTopicDepartmentMappingRepository(db *gorm.DB) domain.TopicDepartmentMappingRepository {
+	return &topicDepartmentMappingRepository{db: db}
+}
+
+// GetByTopic finds the mapping for a topic, ignoring case and surrounding whitespace
+func (r *topicDepartmentMappingRepository) GetByTopic(ctx context.Context, topic string) (*domain.TopicDepartmentMapping, error) {
+	var mapping domain.TopicDepartmentMapping
+	if err := r.db.WithContext(ctx).
+		Preload("Department").
+		First(&mapping, "LOWER(topic) = ?", strings.ToLower(strings.TrimSpace(topic))).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &mapping, nil
+}
+
+func (r *topicDepartmentMappingRepository) GetAll(ctx context.Context) ([]*domain.TopicDepartmentMapping, error) {
+	var mappings []*domain.TopicDepartmentMapping
+	if err := r.db.WithContext(ctx).
+		Preload("Department").
+		Order("topic ASC").
+		Find(&mappings).Error; err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"gorm.io/gorm"
+)
+
+type userMFARepository struct {
+	db *gorm.DB
+}
+
+func NewUserMFARepository(db *gorm.DB) domain.UserMFARepository {
+	return &userMFARepository{db: db}
+}
+
+func (r *userMFARepository) GetByUserID(ctx context.Context, userID string) (*domain.UserMFA, error) {
+	var mfa domain.UserMFA
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		First(&mfa).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+func (r *userMFARepository) Save(ctx context.Context, mfa *domain.UserMFA) error {
+	return r.db.WithContext(ctx).Save(mfa).Error
+}
+
+// UpdateLastUsedStep records an accepted TOTP step. Returns false when the
+// step, or a later one, was already used, i.e. the code is being replayed.
+func (r *userMFARepository) UpdateLastUsedStep(ctx context.Context, userID string, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&domain.UserMFA{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *userMFARepository) DeleteByUserID(ctx context.Context, userID string) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Delete(&domain.UserMFA{}).Error
+}
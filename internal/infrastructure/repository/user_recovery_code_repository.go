@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"gorm.io/gorm"
+)
+
+type userRecoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRecoveryCodeRepository(db *gorm.DB) domain.UserRecoveryCodeRepository {
+	return &userRecoveryCodeRepository{db: db}
+}
+
+// ReplaceForUser deletes the existing codes of the user and stores new ones
+func (r *userRecoveryCodeRepository) ReplaceForUser(ctx context.Context, userID string, codes []*domain.UserRecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.UserRecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// Use consumes an unused code of the user. Returns false when there is none
+// with this hash.
+func (r *userRecoveryCodeRepository) Use(ctx context.Context, userID, codeHash string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&domain.UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *userRecoveryCodeRepository) CountUnused(ctx context.Context, userID string) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&domain.UserRecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *userRecoveryCodeRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Delete(&domain.UserRecoveryCode{}).Error
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+type WaitingQueueRepository struct {
+	redisClient *redis.Client
+}
+
+func NewWaitingQueueRepository(redisClient *redis.Client) *WaitingQueueRepository {
+	return &WaitingQueueRepository{
+		redisClient: redisClient,
+	}
+}
+
+// QueueEntry is the position of a session inside its waiting queue
+type QueueEntry struct {
+	SessionID string
+	Scope     string // department ID or "all"
+	Position  int64  // 1-based
+	Length    int64
+}
+
+// GlobalQueueScope is the queue for sessions that are not routed to a department
+const GlobalQueueScope = "all"
+
+const (
+	waitingQueuePrefix = "queue:waiting:"
+	queueScopesKey     = "queue:scopes" // hash of session ID -> queue scope
+)
+
+// priorityRank orders priorities so that lower ranks leave the queue first
+var priorityRank = map[string]int64{
+	"urgent": 0,
+	"high":   1,
+	"normal": 2,
+	"low":    3,
+}
+
+// QueueScope returns the queue a session belongs to
+func QueueScope(session *domain.ChatSession) string {
+	if session.DepartmentID.Valid {
+		return session.DepartmentID.String
+	}
+	return GlobalQueueScope
+}
+
+// queueScore sorts by priority first, then by start time (millisecond precision)
+func queueScore(session *domain.ChatSession) float64 {
+	rank, ok := priorityRank[session.Priority]
+	if !ok {
+		rank = priorityRank["normal"]
+	}
+	return float64(rank*1e13 + session.StartedAt.UnixMilli())
+}
+
+// Enqueue adds the session to its queue, moving it if it was queued elsewhere
+func (r *WaitingQueueRepository) Enqueue(ctx context.Context, session *domain.ChatSession) error {
+	scope := QueueScope(session)
+
+	previous, err := r.redisClient.HGet(ctx, queueScopesKey, session.ID).Result()
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to get queue scope: %w", err)
+	}
+
+	pipe := r.redisClient.TxPipeline()
+	if previous != "" && previous != scope {
+		pipe.ZRem(ctx, waitingQueuePrefix+previous, session.ID)
+	}
+	pipe.ZAdd(ctx, waitingQueuePrefix+scope, redis.Z{Score: queueScore(session), Member: session.ID})
+	pipe.HSet(ctx, queueScopesKey, session.ID, scope)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to enqueue session: %w", err)
+	}
+	return nil
+}
+
+// Remove takes the session out of whatever queue it is in
+func (r *WaitingQueueRepository) Remove(ctx context.Context, sessionID string) error {
+	scope, err := r.redisClient.HGet(ctx, queueScopesKey, sessionID).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil // Not queued
+		}
+		return fmt.Errorf("failed to get queue scope: %w", err)
+	}
+
+	pipe := r.redisClient.TxPipeline()
+	pipe.ZRem(ctx, waitingQueuePrefix+scope, sessionID)
+	pipe.HDel(ctx, queueScopesKey, sessionID)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to dequeue session: %w", err)
+	}
+	return nil
+}
+
+// GetEntry returns the queue position of a session, or nil when it is not queued
+func (r *WaitingQueueRepository) GetEntry(ctx context.Context, sessionID string) (*QueueEntry, error) {
+	scope, err := r.redisClient.HGet(ctx, queueScopesKey, sessionID).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get queue scope: %w", err)
+	}
+
+	queueKey := waitingQueuePrefix + scope
+	rank, err := r.redisClient.ZRank(ctx, queueKey, sessionID).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get queue position: %w", err)
+	}
+
+	length, err := r.redisClient.ZCard(ctx, queueKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue length: %w", err)
+	}
+
+	return &QueueEntry{
+		SessionID: sessionID,
+		Scope:     scope,
+		Position:  rank + 1,
+		Length:    length,
+	}, nil
+}
+
+// Peek returns the session at the head of the given queues, preferring the
+// lowest score across them. Returns an empty string when all are empty.
+func (r *WaitingQueueRepository) Peek(ctx context.Context, scopes ...string) (string, error) {
+	var head string
+	var headScore float64
+	for _, scope := range scopes {
+		entries, err := r.redisClient.ZRangeWithScores(ctx, waitingQueuePrefix+scope, 0, 0).Result()
+		if err != nil {
+			return "", fmt.Errorf("failed to read queue head: %w", err)
+		}
+
+		if len(entries) == 0 {
+			continue
+		}
+
+		if head == "" || entries[0].Score < headScore {
+			head = entries[0].Member.(string)
+			headScore = entries[0].Score
+		}
+	}
+
+	return head, nil
+}
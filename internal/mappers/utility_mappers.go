@@ -57,6 +57,67 @@ func DepartmentToResponse(entity *entities.Department) *models.DepartmentRespons
 	return response
 }
 
+// DepartmentToDetailResponse converts a Department entity and its agent count to DepartmentDetailResponse
+func DepartmentToDetailResponse(entity *entities.Department, agentCount int) *models.DepartmentDetailResponse {
+	if entity == nil {
+		return nil
+	}
+
+	response := &models.DepartmentDetailResponse{
+		ID:         entity.ID,
+		Name:       entity.Name,
+		IsActive:   entity.IsActive,
+		AgentCount: agentCount,
+		CreatedAt:  FormatTime(entity.CreatedAt),
+		UpdatedAt:  FormatTime(entity.UpdatedAt),
+	}
+
+	// Handle optional description
+	if entity.Description.Valid {
+		response.Description = entity.Description.String
+	}
+
+	return response
+}
+
+// DepartmentsToDetailResponse converts slice of Department entities to DepartmentDetailResponse slice,
+// taking agent counts by department ID
+func DepartmentsToDetailResponse(entities []*entities.Department, agentCounts map[string]int) []models.DepartmentDetailResponse {
+	responses := make([]models.DepartmentDetailResponse, 0, len(entities))
+	for _, entity := range entities {
+		if response := DepartmentToDetailResponse(entity, agentCounts[entity.ID]); response != nil {
+			responses = append(responses, *response)
+		}
+	}
+	return responses
+}
+
+// ChatTagToResponse converts ChatTag entity to ChatTagResponse
+func ChatTagToResponse(entity *entities.ChatTag) *models.ChatTagResponse {
+	if entity == nil {
+		return nil
+	}
+
+	return &models.ChatTagResponse{
+		ID:        entity.ID,
+		Name:      entity.Name,
+		Color:     entity.Color,
+		CreatedAt: FormatTime(entity.CreatedAt),
+		UpdatedAt: FormatTime(entity.UpdatedAt),
+	}
+}
+
+// ChatTagsToResponse converts slice of ChatTag entities to ChatTagResponse slice
+func ChatTagsToResponse(entities []*entities.ChatTag) []models.ChatTagResponse {
+	responses := make([]models.ChatTagResponse, 0, len(entities))
+	for _, entity := range entities {
+		if response := ChatTagToResponse(entity); response != nil {
+			responses = append(responses, *response)
+		}
+	}
+	return responses
+}
+
 // UsersToResponse converts slice of User entity pointers to UserResponse slice
 func UsersToResponse(entities []*entities.User) []models.UserResponse {
 	if entities == nil {
@@ -35,6 +35,26 @@ type DepartmentResponse struct {
 	UpdatedAt   string `json:"updated_at"`
 }
 
+// DepartmentDetailResponse represents a department in the department management API
+type DepartmentDetailResponse struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	IsActive    bool   `json:"is_active"`
+	AgentCount  int    `json:"agent_count"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+// ChatTagResponse represents a clean chat tag response
+type ChatTagResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Color     string `json:"color"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
 // ChatSessionContactResponse represents a clean session contact response
 type ChatSessionContactResponse struct {
 	ID           string `json:"id"`
@@ -73,6 +93,7 @@ type ChatSessionMinimalResponse struct {
 	EndedAt    string            `json:"ended_at,omitempty"`
 	ChatUser   *ChatUserResponse `json:"chat_user,omitempty"`
 	Agent      *UserResponse     `json:"agent,omitempty"`
+	Tags       []ChatTagResponse `json:"tags,omitempty"`
 	CreatedAt  string            `json:"created_at"`
 	UpdatedAt  string            `json:"updated_at"`
 }
@@ -93,6 +114,7 @@ type ChatSessionDetailResponse struct {
 	Department   *DepartmentResponse         `json:"department,omitempty"`
 	Messages     []ChatMessageResponse       `json:"messages,omitempty"`
 	Contact      *ChatSessionContactResponse `json:"contact,omitempty"`
+	Tags         []ChatTagResponse           `json:"tags,omitempty"`
 	CreatedAt    string                      `json:"created_at"`
 	UpdatedAt    string                      `json:"updated_at"`
 }
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"github.com/novianakbar/livechat-be/internal/infrastructure/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// Assignment strategy names, used in ASSIGNMENT_STRATEGY
+const (
+	StrategyLeastActive        = "least_active"
+	StrategyRoundRobin         = "round_robin"
+	StrategyDepartmentWeighted = "department_weighted"
+)
+
+const roundRobinCursorPrefix = "assignment:rr:"
+
+// AgentCandidate is an online agent that can still take a new session
+type AgentCandidate struct {
+	Agent          *domain.User
+	Status         string // online status from Redis
+	ActiveSessions int    // sessions assigned to the agent that are not closed
+}
+
+// AssignmentStrategy picks one agent out of the eligible candidates.
+// scope identifies the queue the session belongs to (department ID or "all").
+type AssignmentStrategy interface {
+	Name() string
+	Select(ctx context.Context, scope string, candidates []AgentCandidate) (*AgentCandidate, error)
+}
+
+// LeastActiveStrategy assigns to the agent with the fewest open sessions
+type LeastActiveStrategy struct{}
+
+func (s *LeastActiveStrategy) Name() string {
+	return StrategyLeastActive
+}
+
+func (s *LeastActiveStrategy) Select(ctx context.Context, scope string, candidates []AgentCandidate) (*AgentCandidate, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	best := &candidates[0]
+	for i := 1; i < len(candidates); i++ {
+		if candidates[i].ActiveSessions < best.ActiveSessions {
+			best = &candidates[i]
+		}
+	}
+	return best, nil
+}
+
+// RoundRobinStrategy rotates through agents using a cursor stored in Redis,
+// so every API instance shares the same rotation
+type RoundRobinStrategy struct {
+	redisClient *redis.Client
+}
+
+func NewRoundRobinStrategy(redisClient *redis.Client) *RoundRobinStrategy {
+	return &RoundRobinStrategy{redisClient: redisClient}
+}
+
+func (s *RoundRobinStrategy) Name() string {
+	return StrategyRoundRobin
+}
+
+func (s *RoundRobinStrategy) Select(ctx context.Context, scope string, candidates []AgentCandidate) (*AgentCandidate, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	// Stable ordering so the cursor points to the same agent across calls
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Agent.ID < candidates[j].Agent.ID
+	})
+
+	cursor, err := s.redisClient.Incr(ctx, roundRobinCursorPrefix+scope).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to advance round-robin cursor: %w", err)
+	}
+
+	return &candidates[int((cursor-1)%int64(len(candidates)))], nil
+}
+
+// DepartmentWeightedStrategy prefers agents with spare capacity, scaled by a
+// per-department weight. Departments without a configured weight count as 1.
+type DepartmentWeightedStrategy struct {
+	weights             map[string]float64
+	maxSessionsPerAgent int
+}
+
+func NewDepartmentWeightedStrategy(weights map[string]float64, maxSessionsPerAgent int) *DepartmentWeightedStrategy {
+	return &DepartmentWeightedStrategy{
+		weights:             weights,
+		maxSessionsPerAgent: maxSessionsPerAgent,
+	}
+}
+
+func (s *DepartmentWeightedStrategy) Name() string {
+	return StrategyDepartmentWeighted
+}
+
+func (s *DepartmentWeightedStrategy) Select(ctx context.Context, scope string, candidates []AgentCandidate) (*AgentCandidate, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	var best *AgentCandidate
+	var bestScore float64
+	for i := range candidates {
+		weight := 1.0
+		if candidates[i].Agent.DepartmentID.Valid {
+			if w, ok := s.weights[candidates[i].Agent.DepartmentID.String]; ok {
+				weight = w
+			}
+		}
+
+		capacity := s.maxSessionsPerAgent - candidates[i].ActiveSessions
+		if s.maxSessionsPerAgent <= 0 {
+			// No cap configured, fall back to inverse load
+			capacity = 1
+			weight = weight / float64(candidates[i].ActiveSessions+1)
+		}
+
+		score := float64(capacity) * weight
+		if best == nil || score > bestScore || (score == bestScore && candidates[i].ActiveSessions < best.ActiveSessions) {
+			best = &candidates[i]
+			bestScore = score
+		}
+	}
+	return best, nil
+}
+
+// NewAssignmentStrategy builds the strategy configured by name, defaulting to least active
+func NewAssignmentStrategy(name string, redisClient *redis.Client, departmentWeights map[string]float64, maxSessionsPerAgent int) AssignmentStrategy {
+	switch name {
+	case StrategyRoundRobin:
+		return NewRoundRobinStrategy(redisClient)
+	case StrategyDepartmentWeighted:
+		return NewDepartmentWeightedStrategy(departmentWeights, maxSessionsPerAgent)
+	default:
+		return &LeastActiveStrategy{}
+	}
+}
+
+// AgentAssignmentService selects agents for new sessions based on Redis
+// presence, current load and the configured strategy
+type AgentAssignmentService struct {
+	userRepo            domain.UserRepository
+	sessionRepo         domain.ChatSessionRepository
+	agentStatusRepo     *repository.AgentStatusRepository
+	strategy            AssignmentStrategy
+	maxSessionsPerAgent int
+}
+
+func NewAgentAssignmentService(
+	userRepo domain.UserRepository,
+	sessionRepo domain.ChatSessionRepository,
+	agentStatusRepo *repository.AgentStatusRepository,
+	strategy AssignmentStrategy,
+	maxSessionsPerAgent int,
+) *AgentAssignmentService {
+	return &AgentAssignmentService{
+		userRepo:            userRepo,
+		sessionRepo:         sessionRepo,
+		agentStatusRepo:     agentStatusRepo,
+		strategy:            strategy,
+		maxSessionsPerAgent: maxSessionsPerAgent,
+	}
+}
+
+// SelectAgent returns the agent that should handle the session, or nil when
+// nobody is online with spare capacity
+func (s *AgentAssignmentService) SelectAgent(ctx context.Context, session *domain.ChatSession) (*domain.User, error) {
+	var departmentID *string
+	scope := "all"
+	if session.DepartmentID.Valid {
+		departmentID = &session.DepartmentID.String
+		scope = session.DepartmentID.String
+	}
+
+	candidates, err := s.GetCandidates(ctx, departmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := s.strategy.Select(ctx, scope, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	if selected == nil {
+		return nil, nil
+	}
+	return selected.Agent, nil
+}
+
+// GetCandidates lists active agents that are online, not busy or away,
+// and below the concurrent session cap
+func (s *AgentAssignmentService) GetCandidates(ctx context.Context, departmentID *string) ([]AgentCandidate, error) {
+	agents, err := s.userRepo.GetAvailableAgents(ctx, departmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(agents) == 0 {
+		return nil, nil
+	}
+
+	onlineAgents, err := s.agentStatusRepo.GetAllOnlineAgents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get online agents: %w", err)
+	}
+
+	presence := make(map[string]string, len(onlineAgents))
+	for _, status := range onlineAgents {
+		presence[status.AgentID] = status.Status
+	}
+
+	agentIDs := make([]string, 0, len(agents))
+	for _, agent := range agents {
+		agentIDs = append(agentIDs, agent.ID)
+	}
+
+	load, err := s.sessionRepo.CountOpenByAgents(ctx, agentIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count agent sessions: %w", err)
+	}
+
+	var candidates []AgentCandidate
+	for _, agent := range agents {
+		status, online := presence[agent.ID]
+		if !online || status == "busy" || status == "away" {
+			continue
+		}
+
+		if s.maxSessionsPerAgent > 0 && load[agent.ID] >= s.maxSessionsPerAgent {
+			continue
+		}
+
+		candidates = append(candidates, AgentCandidate{
+			Agent:          agent,
+			Status:         status,
+			ActiveSessions: load[agent.ID],
+		})
+	}
+
+	return candidates, nil
+}
+
+// CanTakeSession reports whether the agent is online, not busy or away, and
+// still below the concurrent session cap
+func (s *AgentAssignmentService) CanTakeSession(ctx context.Context, agent *domain.User) (bool, error) {
+	agentID, err := uuid.Parse(agent.ID)
+	if err != nil {
+		return false, err
+	}
+
+	status, err := s.agentStatusRepo.GetAgentStatus(ctx, agentID)
+	if err != nil {
+		return false, err
+	}
+
+	if status == nil || status.Status == "busy" || status.Status == "away" {
+		return false, nil
+	}
+
+	if s.maxSessionsPerAgent <= 0 {
+		return true, nil
+	}
+
+	load, err := s.sessionRepo.CountOpenByAgents(ctx, []string{agent.ID})
+	if err != nil {
+		return false, fmt.Errorf("failed to count agent sessions: %w", err)
+	}
+
+	return load[agent.ID] < s.maxSessionsPerAgent, nil
+}
@@ -9,9 +9,15 @@ import (
 	"github.com/novianakbar/livechat-be/internal/infrastructure/repository"
 )
 
+// HeartbeatListener is notified after an agent heartbeat has been stored
+type HeartbeatListener interface {
+	OnAgentHeartbeat(agent *domain.User, status string)
+}
+
 type AgentStatusService struct {
 	agentStatusRepo *repository.AgentStatusRepository
 	userRepo        domain.UserRepository
+	listeners       []HeartbeatListener
 }
 
 func NewAgentStatusService(
@@ -24,6 +30,12 @@ func NewAgentStatusService(
 	}
 }
 
+// AddHeartbeatListener registers a listener for agent heartbeats.
+// Must be called before the server starts handling requests.
+func (s *AgentStatusService) AddHeartbeatListener(listener HeartbeatListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
 // UpdateAgentHeartbeat updates agent's heartbeat and status
 func (s *AgentStatusService) UpdateAgentHeartbeat(ctx context.Context, agentID uuid.UUID, status string) error {
 	// Get agent details from database
@@ -53,7 +65,15 @@ func (s *AgentStatusService) UpdateAgentHeartbeat(ctx context.Context, agentID u
 	}
 
 	// Update status in Redis
-	return s.agentStatusRepo.SetAgentOnline(ctx, agent, status)
+	if err := s.agentStatusRepo.SetAgentOnline(ctx, agent, status); err != nil {
+		return err
+	}
+
+	for _, listener := range s.listeners {
+		listener.OnAgentHeartbeat(agent, status)
+	}
+
+	return nil
 }
 
 // GetOnlineAgents gets all currently online agents
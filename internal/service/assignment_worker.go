@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/domain"
+)
+
+// WaitingSessionAssigner is the part of the chat usecase the worker drives
+type WaitingSessionAssigner interface {
+	GetWaitingSessions(ctx context.Context, departmentID *uuid.UUID) ([]*domain.ChatSession, error)
+	AutoAssignAgent(ctx context.Context, sessionID uuid.UUID) error
+}
+
+// AssignmentWorker periodically retries assignment of sessions that are still
+// waiting, and runs an extra pass whenever an available agent sends a heartbeat
+type AssignmentWorker struct {
+	assigner WaitingSessionAssigner
+	interval time.Duration
+	trigger  chan struct{}
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+func NewAssignmentWorker(assigner WaitingSessionAssigner, interval time.Duration) *AssignmentWorker {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	return &AssignmentWorker{
+		assigner: assigner,
+		interval: interval,
+		trigger:  make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start runs the worker loop in the background until Stop is called
+func (w *AssignmentWorker) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				w.runPass()
+			case <-w.trigger:
+				w.runPass()
+			}
+		}
+	}()
+
+	log.Printf("Assignment worker started (interval %s)", w.interval)
+}
+
+// Stop signals the worker to exit and waits for the current pass to finish
+func (w *AssignmentWorker) Stop() {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	w.wg.Wait()
+	log.Println("Assignment worker stopped")
+}
+
+// OnAgentHeartbeat schedules a pass when an agent that can take chats checks
+// in. Multiple heartbeats before the next pass collapse into one.
+func (w *AssignmentWorker) OnAgentHeartbeat(agent *domain.User, status string) {
+	if agent.Role != "agent" || status != "online" {
+		return
+	}
+
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// runPass tries to assign every waiting session, oldest and most urgent first
+func (w *AssignmentWorker) runPass() {
+	ctx, cancel := context.WithTimeout(context.Background(), w.interval)
+	defer cancel()
+
+	sessions, err := w.assigner.GetWaitingSessions(ctx, nil)
+	if err != nil {
+		log.Printf("Assignment worker: failed to get waiting sessions: %v", err)
+		return
+	}
+
+	for _, session := range sessions {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		// Sessions that already have an agent are waiting for the agent's first reply
+		if session.AgentID.Valid {
+			continue
+		}
+
+		sessionID, err := uuid.Parse(session.ID)
+		if err != nil {
+			continue
+		}
+
+		if err := w.assigner.AutoAssignAgent(ctx, sessionID); err != nil {
+			log.Printf("Assignment worker: failed to assign session %s: %v", session.ID, err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/domain"
+)
+
+// IdleSessionCloser is the part of the chat usecase the idle sweeper drives
+type IdleSessionCloser interface {
+	GetIdleSessions(ctx context.Context, status string, idleSince time.Time) ([]*domain.IdleSession, error)
+	WarnIdleSession(ctx context.Context, sessionID uuid.UUID, closeIn time.Duration) (*domain.ChatMessage, error)
+	CloseIdleSession(ctx context.Context, sessionID uuid.UUID) (*domain.ChatMessage, error)
+}
+
+// ChatMessagePublisher delivers stored chat messages to connected clients
+type ChatMessagePublisher interface {
+	PublishChatMessage(ctx context.Context, message *domain.ChatMessage) error
+}
+
+// IdleSessionJob warns and then closes sessions that had no customer or agent
+// activity for too long. Timeouts are configured per status; zero disables it.
+type IdleSessionJob struct {
+	closer         IdleSessionCloser
+	publisher      ChatMessagePublisher
+	waitingTimeout time.Duration
+	activeTimeout  time.Duration
+	warningBefore  time.Duration
+}
+
+func NewIdleSessionJob(
+	closer IdleSessionCloser,
+	publisher ChatMessagePublisher,
+	waitingTimeout time.Duration,
+	activeTimeout time.Duration,
+	warningBefore time.Duration,
+) *IdleSessionJob {
+	return &IdleSessionJob{
+		closer:         closer,
+		publisher:      publisher,
+		waitingTimeout: waitingTimeout,
+		activeTimeout:  activeTimeout,
+		warningBefore:  warningBefore,
+	}
+}
+
+// Run sweeps waiting and active sessions once
+func (j *IdleSessionJob) Run(ctx context.Context) error {
+	if err := j.sweep(ctx, "waiting", j.waitingTimeout); err != nil {
+		return err
+	}
+	return j.sweep(ctx, "active", j.activeTimeout)
+}
+
+func (j *IdleSessionJob) sweep(ctx context.Context, status string, timeout time.Duration) error {
+	if timeout <= 0 {
+		return nil
+	}
+
+	// Without a usable warning window sessions are closed right at the timeout
+	warningBefore := j.warningBefore
+	if warningBefore < 0 || warningBefore >= timeout {
+		warningBefore = 0
+	}
+
+	now := time.Now()
+	sessions, err := j.closer.GetIdleSessions(ctx, status, now.Add(-(timeout - warningBefore)))
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		sessionID, err := uuid.Parse(session.SessionID)
+		if err != nil {
+			continue
+		}
+
+		idle := now.Sub(session.LastActivityAt)
+		warned := session.WarnedAt != nil && session.WarnedAt.After(session.LastActivityAt)
+
+		var message *domain.ChatMessage
+		switch {
+		case warningBefore > 0 && !warned:
+			// Always warn first, even if the session is already past its timeout
+			closeIn := timeout - idle
+			if closeIn < warningBefore {
+				closeIn = warningBefore
+			}
+			message, err = j.closer.WarnIdleSession(ctx, sessionID, closeIn)
+		case idle >= timeout && (!warned || now.Sub(*session.WarnedAt) >= warningBefore):
+			message, err = j.closer.CloseIdleSession(ctx, sessionID)
+		default:
+			continue
+		}
+
+		if err != nil {
+			log.Printf("Idle sweeper: failed to handle session %s: %v", session.SessionID, err)
+			continue
+		}
+
+		if j.publisher != nil && message != nil {
+			if err := j.publisher.PublishChatMessage(ctx, message); err != nil {
+				log.Printf("Idle sweeper: failed to publish message for session %s: %v", session.SessionID, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is a unit of background work run by the JobScheduler
+type Job func(ctx context.Context) error
+
+type scheduledJob struct {
+	name     string
+	interval time.Duration
+	run      Job
+	// dailyAt is set for jobs that run once a day, as an offset from midnight
+	dailyAt *time.Duration
+}
+
+// JobScheduler runs registered jobs on fixed intervals until stopped.
+// Each job runs in its own goroutine, so a slow job does not delay others.
+type JobScheduler struct {
+	jobs   []scheduledJob
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewJobScheduler() *JobScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &JobScheduler{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Every registers a job to run once per interval. Jobs with a non-positive
+// interval are treated as disabled. Must be called before Start.
+func (s *JobScheduler) Every(name string, interval time.Duration, job Job) {
+	if interval <= 0 {
+		log.Printf("Job %s disabled", name)
+		return
+	}
+
+	s.jobs = append(s.jobs, scheduledJob{
+		name:     name,
+		interval: interval,
+		run:      job,
+	})
+}
+
+// Daily registers a job to run once a day at the given offset from midnight
+// (server time). Offsets outside a single day disable the job. Must be called
+// before Start.
+func (s *JobScheduler) Daily(name string, at time.Duration, job Job) {
+	if at < 0 || at >= 24*time.Hour {
+		log.Printf("Job %s disabled", name)
+		return
+	}
+
+	s.jobs = append(s.jobs, scheduledJob{
+		name:     name,
+		interval: 24 * time.Hour,
+		run:      job,
+		dailyAt:  &at,
+	})
+}
+
+// Start launches all registered jobs
+func (s *JobScheduler) Start() {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		if job.dailyAt != nil {
+			go s.dailyLoop(job)
+			log.Printf("Job %s scheduled daily at %s", job.name, *job.dailyAt)
+			continue
+		}
+		go s.loop(job)
+		log.Printf("Job %s scheduled every %s", job.name, job.interval)
+	}
+}
+
+// Stop cancels running jobs and waits for them to return
+func (s *JobScheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+	log.Println("Job scheduler stopped")
+}
+
+func (s *JobScheduler) loop(job scheduledJob) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(job.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := job.run(s.ctx); err != nil && s.ctx.Err() == nil {
+				log.Printf("Job %s failed: %v", job.name, err)
+			}
+		}
+	}
+}
+
+func (s *JobScheduler) dailyLoop(job scheduledJob) {
+	defer s.wg.Done()
+
+	for {
+		timer := time.NewTimer(time.Until(nextDailyRun(time.Now(), *job.dailyAt)))
+
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			if err := job.run(s.ctx); err != nil && s.ctx.Err() == nil {
+				log.Printf("Job %s failed: %v", job.name, err)
+			}
+		}
+	}
+}
+
+// nextDailyRun returns the first time after now that is at the given offset
+// from midnight in now's location
+func nextDailyRun(now time.Time, at time.Duration) time.Time {
+	year, month, day := now.Date()
+	next := time.Date(year, month, day, 0, 0, 0, 0, now.Location()).Add(at)
+	if !next.After(now) {
+		next = time.Date(year, month, day+1, 0, 0, 0, 0, now.Location()).Add(at)
+	}
+	return next
+}
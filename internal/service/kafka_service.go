@@ -13,7 +13,10 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/domain"
 	"github.com/segmentio/kafka-go"
 )
 
@@ -65,6 +68,52 @@ func (k *KafkaService) PublishMessage(ctx context.Context, msg interface{}) erro
 	})
 }
 
+// PublishChatMessage publishes a stored chat message to Kafka without its GORM associations.
+func (k *KafkaService) PublishChatMessage(ctx context.Context, message *domain.ChatMessage) error {
+	// Parse IDs for Kafka message
+	messageUUID, _ := uuid.Parse(message.ID)
+	sessionUUID, _ := uuid.Parse(message.SessionID)
+
+	// Create a clean message object without GORM associations for Kafka
+	kafkaMessage := struct {
+		ID          uuid.UUID  `json:"id"`
+		SessionID   uuid.UUID  `json:"session_id"`
+		SenderID    *uuid.UUID `json:"sender_id"`
+		SenderType  string     `json:"sender_type"`
+		Message     string     `json:"message"`
+		MessageType string     `json:"message_type"`
+		Attachments []string   `json:"attachments"`
+		ReadAt      *time.Time `json:"read_at"`
+		CreatedAt   time.Time  `json:"created_at"`
+		UpdatedAt   time.Time  `json:"updated_at"`
+	}{
+		ID:        messageUUID,
+		SessionID: sessionUUID,
+		SenderID: func() *uuid.UUID {
+			if message.SenderID.Valid {
+				if parsed, err := uuid.Parse(message.SenderID.String); err == nil {
+					return &parsed
+				}
+			}
+			return nil
+		}(),
+		SenderType:  message.SenderType,
+		Message:     message.Message,
+		MessageType: message.MessageType,
+		Attachments: message.Attachments,
+		ReadAt: func() *time.Time {
+			if message.ReadAt.Valid {
+				return &message.ReadAt.Time
+			}
+			return nil
+		}(),
+		CreatedAt: message.CreatedAt,
+		UpdatedAt: message.UpdatedAt,
+	}
+
+	return k.PublishMessage(ctx, kafkaMessage)
+}
+
 // ConsumeAndBroadcast consumes messages from Kafka and broadcasts to WebSocket clients.
 // func (k *KafkaService) ConsumeAndBroadcast(ctx context.Context, broadcastFunc func([]byte)) {
 // 	for {
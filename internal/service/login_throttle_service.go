@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/novianakbar/livechat-be/internal/infrastructure/repository"
+)
+
+// LoginThrottleService slows down password guessing. Failed logins are counted
+// per email and per client IP; once a counter passes its allowance, every
+// further failure locks that email or IP for twice as long as the last one.
+type LoginThrottleService struct {
+	attemptRepo   *repository.LoginAttemptRepository
+	maxAttempts   int
+	ipMaxAttempts int
+	failureWindow time.Duration
+	baseLockout   time.Duration
+	maxLockout    time.Duration
+}
+
+func NewLoginThrottleService(
+	attemptRepo *repository.LoginAttemptRepository,
+	maxAttempts int,
+	ipMaxAttempts int,
+	failureWindow time.Duration,
+	baseLockout time.Duration,
+	maxLockout time.Duration,
+) *LoginThrottleService {
+	return &LoginThrottleService{
+		attemptRepo:   attemptRepo,
+		maxAttempts:   maxAttempts,
+		ipMaxAttempts: ipMaxAttempts,
+		failureWindow: failureWindow,
+		baseLockout:   baseLockout,
+		maxLockout:    maxLockout,
+	}
+}
+
+// Check returns how long logins for the email or from the IP are still locked
+func (s *LoginThrottleService) Check(ctx context.Context, email, clientIP string) (time.Duration, error) {
+	emailLock, err := s.attemptRepo.LockedFor(ctx, emailAttemptKey(email))
+	if err != nil {
+		return 0, err
+	}
+
+	ipLock, err := s.attemptRepo.LockedFor(ctx, ipAttemptKey(clientIP))
+	if err != nil {
+		return 0, err
+	}
+
+	return max(emailLock, ipLock), nil
+}
+
+// RecordFailure counts a failed login and returns the lockout it caused, if any
+func (s *LoginThrottleService) RecordFailure(ctx context.Context, email, clientIP string) (time.Duration, error) {
+	emailLock, err := s.recordFailure(ctx, emailAttemptKey(email), s.maxAttempts)
+	if err != nil {
+		return 0, err
+	}
+
+	ipLock, err := s.recordFailure(ctx, ipAttemptKey(clientIP), s.ipMaxAttempts)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(emailLock, ipLock), nil
+}
+
+// RecordSuccess forgets earlier failures for the email. The IP counter is kept
+// so one valid account cannot be used to reset guessing against others.
+func (s *LoginThrottleService) RecordSuccess(ctx context.Context, email string) error {
+	return s.attemptRepo.Reset(ctx, emailAttemptKey(email))
+}
+
+// Unlock lifts the lockout of an account and clears its failures
+func (s *LoginThrottleService) Unlock(ctx context.Context, email string) error {
+	return s.attemptRepo.Reset(ctx, emailAttemptKey(email))
+}
+
+func (s *LoginThrottleService) recordFailure(ctx context.Context, key string, allowed int) (time.Duration, error) {
+	if allowed <= 0 {
+		return 0, nil
+	}
+
+	failures, err := s.attemptRepo.RecordFailure(ctx, key, s.failureWindow)
+	if err != nil {
+		return 0, err
+	}
+
+	if failures < int64(allowed) {
+		return 0, nil
+	}
+
+	lockout := s.lockoutFor(failures - int64(allowed))
+	if err := s.attemptRepo.Lock(ctx, key, lockout); err != nil {
+		return 0, err
+	}
+	return lockout, nil
+}
+
+// lockoutFor doubles the base lockout for every failure past the allowance
+func (s *LoginThrottleService) lockoutFor(excess int64) time.Duration {
+	lockout := s.baseLockout
+	for i := int64(0); i < excess && lockout < s.maxLockout; i++ {
+		lockout *= 2
+	}
+	return min(lockout, s.maxLockout)
+}
+
+func emailAttemptKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipAttemptKey(clientIP string) string {
+	return "ip:" + clientIP
+}
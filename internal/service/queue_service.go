@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"github.com/novianakbar/livechat-be/internal/infrastructure/repository"
+)
+
+const (
+	// handleTimeWindow is how far back closed sessions are used for the ETA
+	handleTimeWindow = 24 * time.Hour
+	// defaultHandleTime is used when there is no recent history (in seconds)
+	defaultHandleTime = 300.0
+)
+
+// QueueService keeps waiting sessions ordered by priority and start time and
+// estimates how long a customer still has to wait
+type QueueService struct {
+	queueRepo           *repository.WaitingQueueRepository
+	sessionRepo         domain.ChatSessionRepository
+	agentStatusRepo     *repository.AgentStatusRepository
+	maxSessionsPerAgent int
+}
+
+func NewQueueService(
+	queueRepo *repository.WaitingQueueRepository,
+	sessionRepo domain.ChatSessionRepository,
+	agentStatusRepo *repository.AgentStatusRepository,
+	maxSessionsPerAgent int,
+) *QueueService {
+	return &QueueService{
+		queueRepo:           queueRepo,
+		sessionRepo:         sessionRepo,
+		agentStatusRepo:     agentStatusRepo,
+		maxSessionsPerAgent: maxSessionsPerAgent,
+	}
+}
+
+// Enqueue puts a waiting session into the queue of its department
+func (s *QueueService) Enqueue(ctx context.Context, session *domain.ChatSession) error {
+	return s.queueRepo.Enqueue(ctx, session)
+}
+
+// Remove takes a session out of the queue once it is assigned or closed
+func (s *QueueService) Remove(ctx context.Context, sessionID string) error {
+	return s.queueRepo.Remove(ctx, sessionID)
+}
+
+// NextForAgent returns the first waiting session the agent may take: the head
+// of the agent's department queue or of the global queue, whichever is older
+// or more urgent. Returns an empty string when nothing is waiting.
+func (s *QueueService) NextForAgent(ctx context.Context, agent *domain.User) (string, error) {
+	scopes := []string{repository.GlobalQueueScope}
+	if agent.DepartmentID.Valid {
+		scopes = append(scopes, agent.DepartmentID.String)
+	}
+	return s.queueRepo.Peek(ctx, scopes...)
+}
+
+// GetQueueStatus reports the position and estimated wait of a session.
+// Waiting sessions missing from Redis (e.g. after a flush) are re-enqueued.
+func (s *QueueService) GetQueueStatus(ctx context.Context, session *domain.ChatSession) (*domain.QueueStatusResponse, error) {
+	sessionID, _ := uuid.Parse(session.ID)
+	response := &domain.QueueStatusResponse{
+		SessionID: sessionID,
+		Status:    session.Status,
+	}
+
+	if session.DepartmentID.Valid {
+		if departmentID, err := uuid.Parse(session.DepartmentID.String); err == nil {
+			response.DepartmentID = &departmentID
+		}
+	}
+
+	if session.Status != "waiting" {
+		return response, nil
+	}
+
+	entry, err := s.queueRepo.GetEntry(ctx, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry == nil {
+		if err := s.queueRepo.Enqueue(ctx, session); err != nil {
+			return nil, err
+		}
+
+		entry, err = s.queueRepo.GetEntry(ctx, session.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if entry == nil {
+			return response, nil
+		}
+	}
+
+	eta, err := s.estimateWait(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	response.InQueue = true
+	response.Position = int(entry.Position)
+	response.QueueLength = int(entry.Length)
+	response.EstimatedWaitSeconds = eta
+
+	return response, nil
+}
+
+// estimateWait assumes a slot frees up every average handle time divided by
+// the number of concurrent slots of the online agents serving the queue
+func (s *QueueService) estimateWait(ctx context.Context, entry *repository.QueueEntry) (int, error) {
+	var departmentID *string
+	var agents []repository.AgentOnlineStatus
+	var err error
+	if entry.Scope == repository.GlobalQueueScope {
+		agents, err = s.agentStatusRepo.GetAllOnlineAgents(ctx)
+	} else {
+		departmentID = &entry.Scope
+		departmentUUID, parseErr := uuid.Parse(entry.Scope)
+		if parseErr != nil {
+			return 0, fmt.Errorf("invalid queue scope: %w", parseErr)
+		}
+		agents, err = s.agentStatusRepo.GetOnlineAgentsByDepartment(ctx, departmentUUID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get online agents: %w", err)
+	}
+
+	since := time.Now().Add(-handleTimeWindow)
+	handleTime, err := s.sessionRepo.GetAverageHandleTime(ctx, domain.SessionMetricsFilter{
+		StartDate:    &since,
+		DepartmentID: departmentID,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get average handle time: %w", err)
+	}
+
+	if handleTime <= 0 {
+		handleTime = defaultHandleTime
+	}
+
+	available := 0
+	for _, agent := range agents {
+		if agent.Status != "away" {
+			available++
+		}
+	}
+
+	slots := available
+	if s.maxSessionsPerAgent > 0 {
+		slots *= s.maxSessionsPerAgent
+	}
+	if slots == 0 {
+		slots = 1
+	}
+
+	return int(math.Ceil(float64(entry.Position) * handleTime / float64(slots))), nil
+}
@@ -2,31 +2,49 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"sort"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/novianakbar/livechat-be/internal/domain"
+	"github.com/novianakbar/livechat-be/pkg/utils"
 )
 
+// rollupLookbackDays is how many past days the nightly rollup recomputes, so
+// sessions closed after midnight still end up in their start day
+const rollupLookbackDays = 2
+
 type AnalyticsUsecase struct {
-	sessionRepo domain.ChatSessionRepository
-	messageRepo domain.ChatMessageRepository
-	userRepo    domain.UserRepository
+	sessionRepo    domain.ChatSessionRepository
+	messageRepo    domain.ChatMessageRepository
+	userRepo       domain.UserRepository
+	ratingRepo     domain.SessionRatingRepository
+	analyticsRepo  domain.ChatAnalyticsRepository
+	departmentRepo domain.DepartmentRepository
 }
 
 func NewAnalyticsUsecase(
 	sessionRepo domain.ChatSessionRepository,
 	messageRepo domain.ChatMessageRepository,
 	userRepo domain.UserRepository,
+	ratingRepo domain.SessionRatingRepository,
+	analyticsRepo domain.ChatAnalyticsRepository,
+	departmentRepo domain.DepartmentRepository,
 ) *AnalyticsUsecase {
 	return &AnalyticsUsecase{
-		sessionRepo: sessionRepo,
-		messageRepo: messageRepo,
-		userRepo:    userRepo,
+		sessionRepo:    sessionRepo,
+		messageRepo:    messageRepo,
+		userRepo:       userRepo,
+		ratingRepo:     ratingRepo,
+		analyticsRepo:  analyticsRepo,
+		departmentRepo: departmentRepo,
 	}
 }
 
-func (u *AnalyticsUsecase) GetDashboardStats() (*domain.DashboardStats, error) {
-	ctx := context.Background()
+// GetDashboardStats returns live counters plus response times, CSAT, top
+// questions and categories for sessions in the [start, end) window
+func (u *AnalyticsUsecase) GetDashboardStats(ctx context.Context, start, end time.Time) (*domain.DashboardStats, error) {
 	stats := &domain.DashboardStats{}
 
 	// Get active sessions count
@@ -44,19 +62,23 @@ func (u *AnalyticsUsecase) GetDashboardStats() (*domain.DashboardStats, error) {
 	stats.WaitingSessions = int(waitingSessions)
 
 	// Get completed sessions today
-	today := time.Now().Truncate(24 * time.Hour)
+	today := utils.StartOfDay(time.Now())
 	completedToday, err := u.sessionRepo.CountCompletedSince(ctx, today)
 	if err != nil {
 		return nil, err
 	}
 	stats.CompletedToday = int(completedToday)
 
-	// Get average response time
-	avgResponseTime, err := u.sessionRepo.GetAverageResponseTime(ctx)
+	// Get average first response and handle time
+	timings, err := u.GetSessionTimings(ctx, domain.SessionMetricsFilter{
+		StartDate: &start,
+		EndDate:   &end,
+	})
 	if err != nil {
 		return nil, err
 	}
-	stats.AverageResponseTime = int(avgResponseTime)
+	stats.AverageResponseTime = int(timings.AverageResponseTime)
+	stats.AverageHandleTime = int(timings.AverageHandleTime)
 
 	// Get total agents
 	totalAgents, err := u.userRepo.CountByRole(ctx, "agent")
@@ -72,15 +94,23 @@ func (u *AnalyticsUsecase) GetDashboardStats() (*domain.DashboardStats, error) {
 	}
 	stats.OnlineAgents = int(onlineAgents)
 
-	// Get top questions (based on frequent topics/messages)
-	topQuestions, err := u.messageRepo.GetTopQuestions(ctx, 5)
+	// Get customer satisfaction
+	ratings, err := u.ratingRepo.GetSummary(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	stats.AverageRating = ratings.AverageRating
+	stats.TotalRatings = ratings.TotalRatings
+
+	// Get top questions (clustered first customer messages)
+	topQuestions, err := u.messageRepo.GetTopQuestions(ctx, start, end, 5)
 	if err != nil {
 		return nil, err
 	}
 	stats.TopQuestions = topQuestions
 
 	// Get OSS categories statistics
-	ossCategories, err := u.sessionRepo.GetOSSCategoriesStats(ctx)
+	ossCategories, err := u.sessionRepo.GetOSSCategoriesStats(ctx, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -89,9 +119,300 @@ func (u *AnalyticsUsecase) GetDashboardStats() (*domain.DashboardStats, error) {
 	return stats, nil
 }
 
-func (u *AnalyticsUsecase) GetAnalytics(req *domain.GetAnalyticsRequest) ([]*domain.ChatAnalytics, error) {
-	// Implementation for detailed analytics
-	// This would get analytics data based on the request parameters
-	// For now, return empty array
-	return []*domain.ChatAnalytics{}, nil
+// GetAnalytics builds the analytics report from the daily rollup. Today is not
+// rolled up yet, so it is aggregated live when the range includes it.
+// StartDate and EndDate are inclusive days.
+func (u *AnalyticsUsecase) GetAnalytics(ctx context.Context, req *domain.AnalyticsRequest) (*domain.AnalyticsResponse, error) {
+	startDay := utils.StartOfDay(req.StartDate)
+	endDay := utils.StartOfDay(req.EndDate)
+	if endDay.Before(startDay) {
+		return nil, errors.New("start date must not be after end date")
+	}
+
+	var rows []*domain.ChatAnalyticsRollup
+	var err error
+	switch {
+	case req.AgentID != nil:
+		rows, err = u.analyticsRepo.GetByAgentAndDateRange(ctx, *req.AgentID, startDay, endDay)
+	case req.DepartmentID != nil:
+		rows, err = u.analyticsRepo.GetByDepartmentAndDateRange(ctx, *req.DepartmentID, startDay, endDay)
+	default:
+		rows, err = u.analyticsRepo.GetByDateRange(ctx, startDay, endDay)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	today := utils.StartOfDay(time.Now())
+	if !today.Before(startDay) && !today.After(endDay) {
+		live, err := u.analyticsRepo.AggregateDay(ctx, today)
+		if err != nil {
+			return nil, err
+		}
+
+		merged := make([]*domain.ChatAnalyticsRollup, 0, len(rows)+len(live))
+		for _, row := range rows {
+			if !utils.StartOfDay(row.Date).Equal(today) {
+				merged = append(merged, row)
+			}
+		}
+		merged = append(merged, live...)
+		rows = merged
+	}
+
+	rows = filterAnalyticsRows(rows, req)
+
+	response := &domain.AnalyticsResponse{
+		DailyAnalytics:      []domain.DailyAnalytics{},
+		AgentPerformance:    []domain.AgentPerformance{},
+		DepartmentAnalytics: []domain.DepartmentAnalytics{},
+	}
+
+	daily := make(map[time.Time]*domain.DailyAnalytics)
+	agents := make(map[string]*domain.AgentPerformance)
+	departments := make(map[string]*domain.DepartmentAnalytics)
+	departmentAgents := make(map[string]map[string]bool)
+	// Response times are averaged over the sessions that got a reply
+	agentResponded := make(map[string]int)
+	var responseTimeTotal float64
+	var respondedSessions int
+
+	for _, row := range rows {
+		response.TotalSessions += row.TotalSessions
+		response.CompletedSessions += row.CompletedSessions
+		response.TotalMessages += row.TotalMessages
+		responseTimeTotal += row.AverageResponseTime * float64(row.RespondedSessions)
+		respondedSessions += row.RespondedSessions
+
+		day := utils.StartOfDay(row.Date)
+		if daily[day] == nil {
+			daily[day] = &domain.DailyAnalytics{Date: day}
+		}
+		daily[day].TotalSessions += row.TotalSessions
+		daily[day].CompletedSessions += row.CompletedSessions
+		daily[day].TotalMessages += row.TotalMessages
+
+		if row.AgentID.Valid {
+			agent := agents[row.AgentID.String]
+			if agent == nil {
+				agent = &domain.AgentPerformance{}
+				agents[row.AgentID.String] = agent
+			}
+			// Weighted sum for now, divided by the responded sessions below
+			agent.AverageResponseTime += row.AverageResponseTime * float64(row.RespondedSessions)
+			agentResponded[row.AgentID.String] += row.RespondedSessions
+			agent.TotalSessions += row.TotalSessions
+			agent.CompletedSessions += row.CompletedSessions
+			agent.TotalMessages += row.TotalMessages
+		}
+
+		if row.DepartmentID.Valid {
+			department := departments[row.DepartmentID.String]
+			if department == nil {
+				department = &domain.DepartmentAnalytics{}
+				departments[row.DepartmentID.String] = department
+				departmentAgents[row.DepartmentID.String] = make(map[string]bool)
+			}
+			department.TotalSessions += row.TotalSessions
+			department.CompletedSessions += row.CompletedSessions
+			department.TotalMessages += row.TotalMessages
+			if row.AgentID.Valid {
+				departmentAgents[row.DepartmentID.String][row.AgentID.String] = true
+			}
+		}
+	}
+
+	if respondedSessions > 0 {
+		response.AverageResponseTime = responseTimeTotal / float64(respondedSessions)
+	}
+
+	for _, day := range daily {
+		response.DailyAnalytics = append(response.DailyAnalytics, *day)
+	}
+	sort.Slice(response.DailyAnalytics, func(i, j int) bool {
+		return response.DailyAnalytics[i].Date.Before(response.DailyAnalytics[j].Date)
+	})
+
+	// Ratings are keyed by when they were given, over the same days
+	ratings, err := u.ratingRepo.GetSummaryByAgent(ctx, startDay, endDay.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	for agentID, performance := range agents {
+		agent, err := u.userRepo.GetByID(ctx, agentID)
+		if err != nil {
+			return nil, err
+		}
+
+		performance.Agent = agent
+		if agentResponded[agentID] > 0 {
+			performance.AverageResponseTime /= float64(agentResponded[agentID])
+		}
+		performance.AverageRating = ratings[agentID].AverageRating
+		performance.TotalRatings = ratings[agentID].TotalRatings
+		response.AgentPerformance = append(response.AgentPerformance, *performance)
+	}
+	sort.Slice(response.AgentPerformance, func(i, j int) bool {
+		return response.AgentPerformance[i].TotalSessions > response.AgentPerformance[j].TotalSessions
+	})
+
+	if len(departments) > 0 {
+		allDepartments, err := u.departmentRepo.GetAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		departmentsByID := make(map[string]*domain.Department, len(allDepartments))
+		for _, department := range allDepartments {
+			departmentsByID[department.ID] = department
+		}
+
+		for departmentID, analytics := range departments {
+			analytics.Department = departmentsByID[departmentID]
+			analytics.ActiveAgents = len(departmentAgents[departmentID])
+			response.DepartmentAnalytics = append(response.DepartmentAnalytics, *analytics)
+		}
+		sort.Slice(response.DepartmentAnalytics, func(i, j int) bool {
+			return response.DepartmentAnalytics[i].TotalSessions > response.DepartmentAnalytics[j].TotalSessions
+		})
+	}
+
+	// Status, priority and tag breakdowns are not part of the rollup
+	filter := domain.SessionMetricsFilter{
+		StartDate: &startDay,
+		EndDate: func() *time.Time {
+			end := endDay.AddDate(0, 0, 1)
+			return &end
+		}(),
+	}
+	if req.AgentID != nil {
+		agentID := req.AgentID.String()
+		filter.AgentID = &agentID
+	}
+	if req.DepartmentID != nil {
+		departmentID := req.DepartmentID.String()
+		filter.DepartmentID = &departmentID
+	}
+
+	response.SessionsByStatus, err = u.sessionRepo.CountGroupedBy(ctx, "status", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	response.SessionsByPriority, err = u.sessionRepo.CountGroupedBy(ctx, "priority", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	response.SessionsByTag, err = u.sessionRepo.CountGroupedBy(ctx, "tag", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// filterAnalyticsRows applies the agent and department filters that the
+// repository query did not already cover
+func filterAnalyticsRows(rows []*domain.ChatAnalyticsRollup, req *domain.AnalyticsRequest) []*domain.ChatAnalyticsRollup {
+	if req.AgentID == nil && req.DepartmentID == nil {
+		return rows
+	}
+
+	filtered := make([]*domain.ChatAnalyticsRollup, 0, len(rows))
+	for _, row := range rows {
+		if req.AgentID != nil && (!row.AgentID.Valid || row.AgentID.String != req.AgentID.String()) {
+			continue
+		}
+		if req.DepartmentID != nil && (!row.DepartmentID.Valid || row.DepartmentID.String != req.DepartmentID.String()) {
+			continue
+		}
+		filtered = append(filtered, row)
+	}
+	return filtered
+}
+
+// RollupDay replaces the stored analytics rows of one day
+func (u *AnalyticsUsecase) RollupDay(ctx context.Context, day time.Time) error {
+	rows, err := u.analyticsRepo.AggregateDay(ctx, day)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		uuidV7, _ := uuid.NewV7()
+		row.ID = uuidV7.String()
+		row.CreatedAt = time.Now()
+		row.UpdatedAt = time.Now()
+	}
+
+	return u.analyticsRepo.ReplaceDay(ctx, day, rows)
+}
+
+// RunDailyRollup rolls up the last few completed days. Meant to run nightly.
+func (u *AnalyticsUsecase) RunDailyRollup(ctx context.Context) error {
+	today := utils.StartOfDay(time.Now())
+	for i := rollupLookbackDays; i >= 1; i-- {
+		if err := u.RollupDay(ctx, today.AddDate(0, 0, -i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAgentPerformance returns session, message and CSAT figures for every
+// agent for sessions started in the period
+func (u *AnalyticsUsecase) GetAgentPerformance(ctx context.Context, start, end time.Time) ([]domain.AgentPerformance, error) {
+	agents, err := u.userRepo.GetByRole(ctx, "agent")
+	if err != nil {
+		return nil, err
+	}
+
+	sessionStats, err := u.sessionRepo.GetAgentSessionStats(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	ratings, err := u.ratingRepo.GetSummaryByAgent(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	performance := make([]domain.AgentPerformance, 0, len(agents))
+	for _, agent := range agents {
+		stats := sessionStats[agent.ID]
+		rating := ratings[agent.ID]
+
+		performance = append(performance, domain.AgentPerformance{
+			Agent:               agent,
+			TotalSessions:       stats.TotalSessions,
+			CompletedSessions:   stats.CompletedSessions,
+			TotalMessages:       stats.TotalMessages,
+			AverageResponseTime: stats.AverageResponseTime,
+			AverageHandleTime:   stats.AverageHandleTime,
+			AverageRating:       rating.AverageRating,
+			TotalRatings:        rating.TotalRatings,
+		})
+	}
+
+	return performance, nil
+}
+
+// GetSessionTimings returns the average first response and handle time in seconds
+func (u *AnalyticsUsecase) GetSessionTimings(ctx context.Context, filter domain.SessionMetricsFilter) (*domain.SessionTimingStats, error) {
+	responseTime, err := u.sessionRepo.GetAverageResponseTime(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	handleTime, err := u.sessionRepo.GetAverageHandleTime(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.SessionTimingStats{
+		AverageResponseTime: responseTime,
+		AverageHandleTime:   handleTime,
+	}, nil
 }
@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"log"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,27 +14,116 @@ import (
 	"github.com/novianakbar/livechat-be/pkg/utils"
 )
 
+const minPasswordLength = 6
+
+// ErrPasswordTooShort rejects new passwords shorter than minPasswordLength
+var ErrPasswordTooShort = fmt.Errorf("password must be at least %d characters", minPasswordLength)
+
 type AgentSessionRepository interface {
 	SetAgentLoggedIn(ctx context.Context, agentID string) error
 	SetAgentLoggedOut(ctx context.Context, agentID string) error
 }
 
+// TokenDenylist stores revoked tokens by jti and per-user revocations
+type TokenDenylist interface {
+	Revoke(ctx context.Context, jti string, ttl time.Duration) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	RevokeAllForUser(ctx context.Context, userID string, at time.Time, ttl time.Duration) error
+	RevokedBefore(ctx context.Context, userID string) (time.Time, error)
+}
+
+// RefreshTokenFamilies tracks which refresh token of each login may still be
+// exchanged, so every refresh token is single-use
+type RefreshTokenFamilies interface {
+	Create(ctx context.Context, familyID, userID, jti string, ttl time.Duration) error
+	Rotate(ctx context.Context, familyID, jti, nextJTI string, ttl time.Duration) (rotated bool, reused bool, err error)
+	Revoke(ctx context.Context, familyID string, ttl time.Duration) error
+	IsRevoked(ctx context.Context, familyID string) (bool, error)
+}
+
+// LoginSessions is the registry of signed-in devices. A login session shares
+// its ID with the refresh token family of the login.
+type LoginSessions interface {
+	Create(ctx context.Context, session *domain.LoginSession, ttl time.Duration) error
+	Touch(ctx context.Context, sessionID string, at time.Time) error
+	Extend(ctx context.Context, userID, sessionID string, ttl time.Duration) error
+	Get(ctx context.Context, sessionID string) (*domain.LoginSession, error)
+	GetByUser(ctx context.Context, userID string) ([]*domain.LoginSession, error)
+	Delete(ctx context.Context, userID, sessionID string) error
+}
+
+// LoginThrottle counts failed logins per email and client IP and locks them
+// out temporarily
+type LoginThrottle interface {
+	Check(ctx context.Context, email, clientIP string) (time.Duration, error)
+	RecordFailure(ctx context.Context, email, clientIP string) (time.Duration, error)
+	RecordSuccess(ctx context.Context, email string) error
+	Unlock(ctx context.Context, email string) error
+}
+
+// MFAVerifier checks the second factor of users who enrolled in MFA
+type MFAVerifier interface {
+	IsEnabled(ctx context.Context, userID string) (bool, error)
+	EnrollmentRequired(ctx context.Context, user *domain.User) (bool, error)
+	VerifyCode(ctx context.Context, userID, code string) (bool, error)
+}
+
+// LoginLockedError is returned while an email or IP is locked out after too
+// many failed logins
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return "too many failed login attempts, try again later"
+}
+
 type AuthUsecase struct {
 	userRepo         domain.UserRepository
 	agentSessionRepo AgentSessionRepository
+	tokenDenylist    TokenDenylist
+	refreshFamilies  RefreshTokenFamilies
+	loginSessions    LoginSessions
+	loginThrottle    LoginThrottle
+	mfa              MFAVerifier
 	jwtUtil          *utils.JWTUtil
+	mfaTokenTTL      time.Duration
 }
 
-func NewAuthUsecase(userRepo domain.UserRepository, agentSessionRepo AgentSessionRepository, jwtUtil *utils.JWTUtil) *AuthUsecase {
+func NewAuthUsecase(
+	userRepo domain.UserRepository,
+	agentSessionRepo AgentSessionRepository,
+	tokenDenylist TokenDenylist,
+	refreshFamilies RefreshTokenFamilies,
+	loginSessions LoginSessions,
+	loginThrottle LoginThrottle,
+	mfa MFAVerifier,
+	jwtUtil *utils.JWTUtil,
+	mfaTokenTTL time.Duration,
+) *AuthUsecase {
 	return &AuthUsecase{
 		userRepo:         userRepo,
 		agentSessionRepo: agentSessionRepo,
+		tokenDenylist:    tokenDenylist,
+		refreshFamilies:  refreshFamilies,
+		loginSessions:    loginSessions,
+		loginThrottle:    loginThrottle,
+		mfa:              mfa,
 		jwtUtil:          jwtUtil,
+		mfaTokenTTL:      mfaTokenTTL,
 	}
 }
 
 func (uc *AuthUsecase) Login(ctx context.Context, req *domain.LoginRequest, clientIP, userAgent string) (*domain.LoginResponse, error) {
-	// TODO: Implement rate limiting based on clientIP and userAgent
+	// Locked out emails and IPs are rejected before the password is checked
+	lockedFor, err := uc.loginThrottle.Check(ctx, req.Email, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	if lockedFor > 0 {
+		return nil, &LoginLockedError{RetryAfter: lockedFor}
+	}
 
 	// Find user by email
 	user, err := uc.userRepo.GetByEmail(ctx, req.Email)
@@ -39,8 +131,9 @@ func (uc *AuthUsecase) Login(ctx context.Context, req *domain.LoginRequest, clie
 		return nil, err
 	}
 
-	if user == nil {
-		return nil, errors.New("invalid email or password")
+	// Unknown emails count as failures too, so they cannot be told apart
+	if user == nil || !utils.CheckPasswordHash(req.Password, user.Password) {
+		return nil, uc.loginFailed(ctx, req.Email, clientIP)
 	}
 
 	// Check if user is active
@@ -48,21 +141,138 @@ func (uc *AuthUsecase) Login(ctx context.Context, req *domain.LoginRequest, clie
 		return nil, errors.New("user account is inactive")
 	}
 
-	// Verify password
-	if !utils.CheckPasswordHash(req.Password, user.Password) {
-		return nil, errors.New("invalid email or password")
+	return uc.LoginVerifiedUser(ctx, user, clientIP, userAgent)
+}
+
+// LoginVerifiedUser continues a login once the user proved who they are, by
+// password or through SSO. Users with MFA still get the second step.
+func (uc *AuthUsecase) LoginVerifiedUser(ctx context.Context, user *domain.User, clientIP, userAgent string) (*domain.LoginResponse, error) {
+	// With MFA the first factor only earns a challenge token for the second step
+	mfaEnabled, err := uc.mfa.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if mfaEnabled {
+		mfaToken, _, err := uc.jwtUtil.GenerateMFAToken(user.ID, uc.mfaTokenTTL)
+		if err != nil {
+			return nil, err
+		}
+
+		return &domain.LoginResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		}, nil
+	}
+
+	if err := uc.loginThrottle.RecordSuccess(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
+	return uc.issueLogin(ctx, user, clientIP, userAgent)
+}
+
+// CompleteMFALogin finishes a login with the MFA challenge token from Login
+// and a TOTP or recovery code. Wrong codes count as failed logins.
+func (uc *AuthUsecase) CompleteMFALogin(ctx context.Context, req *domain.MFALoginRequest, clientIP, userAgent string) (*domain.LoginResponse, error) {
+	claims, err := uc.jwtUtil.ValidateMFAToken(req.MFAToken)
+	if err != nil {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+
+	revoked, err := uc.tokenDenylist.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil || !user.IsActive {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+
+	lockedFor, err := uc.loginThrottle.Check(ctx, user.Email, clientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	if lockedFor > 0 {
+		return nil, &LoginLockedError{RetryAfter: lockedFor}
+	}
+
+	valid, err := uc.mfa.VerifyCode(ctx, user.ID, req.Code)
+	if err != nil {
+		return nil, err
 	}
 
+	if !valid {
+		lockout, err := uc.loginThrottle.RecordFailure(ctx, user.Email, clientIP)
+		if err != nil {
+			return nil, err
+		}
+
+		if lockout > 0 {
+			log.Printf("Login locked for %s from %s for %s after repeated failed MFA codes", user.Email, clientIP, lockout)
+			return nil, &LoginLockedError{RetryAfter: lockout}
+		}
+		return nil, errors.New("invalid verification code")
+	}
+
+	// The challenge token is single-use
+	if err := uc.tokenDenylist.Revoke(ctx, claims.ID, time.Until(claims.ExpiresAt.Time)); err != nil {
+		return nil, err
+	}
+
+	if err := uc.loginThrottle.RecordSuccess(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
+	return uc.issueLogin(ctx, user, clientIP, userAgent)
+}
+
+// MFAEnrollmentPending reports whether the user's role requires MFA that the
+// user has not set up yet
+func (uc *AuthUsecase) MFAEnrollmentPending(ctx context.Context, user *domain.User) (bool, error) {
+	return uc.mfa.EnrollmentRequired(ctx, user)
+}
+
+// issueLogin hands out the tokens of a fully authenticated login
+func (uc *AuthUsecase) issueLogin(ctx context.Context, user *domain.User, clientIP, userAgent string) (*domain.LoginResponse, error) {
 	// Generate JWT token pair
 	var departmentID *string
 	if user.DepartmentID.Valid {
 		departmentID = &user.DepartmentID.String
 	}
-	tokenPair, err := uc.jwtUtil.GenerateTokenPair(user.ID, user.Email, user.Role, departmentID)
+	tokenPair, err := uc.jwtUtil.GenerateTokenPair(user.ID, user.Email, user.Role, departmentID, "")
 	if err != nil {
 		return nil, err
 	}
 
+	// Every login starts a new refresh token family and a session for the device
+	sessionTTL := time.Until(tokenPair.RefreshExpiresAt)
+	if err := uc.refreshFamilies.Create(ctx, tokenPair.FamilyID, user.ID, tokenPair.RefreshTokenID, sessionTTL); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := uc.loginSessions.Create(ctx, &domain.LoginSession{
+		SessionID: tokenPair.FamilyID,
+		UserID:    user.ID,
+		ClientIP:  clientIP,
+		UserAgent: userAgent,
+		LoginTime: now,
+		LastSeen:  now,
+	}, sessionTTL); err != nil {
+		return nil, err
+	}
+
 	// Track agent login in database if user is agent or admin
 	if user.Role == "agent" || user.Role == "admin" {
 		if err := uc.agentSessionRepo.SetAgentLoggedIn(ctx, user.ID); err != nil {
@@ -71,20 +281,54 @@ func (uc *AuthUsecase) Login(ctx context.Context, req *domain.LoginRequest, clie
 		}
 	}
 
-	// TODO: Store session info in Redis/database for revocation support
+	enrollmentRequired, err := uc.mfa.EnrollmentRequired(ctx, user)
+	if err != nil {
+		return nil, err
+	}
 
 	// Hide password from response
 	user.Password = ""
 
 	return &domain.LoginResponse{
-		AccessToken:  tokenPair.AccessToken,
-		RefreshToken: tokenPair.RefreshToken,
-		ExpiresIn:    tokenPair.ExpiresIn,
-		ExpiresAt:    tokenPair.ExpiresAt,
-		User:         user,
+		AccessToken:           tokenPair.AccessToken,
+		RefreshToken:          tokenPair.RefreshToken,
+		ExpiresIn:             tokenPair.ExpiresIn,
+		ExpiresAt:             tokenPair.ExpiresAt,
+		SessionID:             tokenPair.FamilyID,
+		User:                  user,
+		MFAEnrollmentRequired: enrollmentRequired,
 	}, nil
 }
 
+// loginFailed records a failed login and returns the error to report
+func (uc *AuthUsecase) loginFailed(ctx context.Context, email, clientIP string) error {
+	lockout, err := uc.loginThrottle.RecordFailure(ctx, email, clientIP)
+	if err != nil {
+		return err
+	}
+
+	if lockout > 0 {
+		log.Printf("Login locked for %s from %s for %s after repeated failures", email, clientIP, lockout)
+		return &LoginLockedError{RetryAfter: lockout}
+	}
+
+	return errors.New("invalid email or password")
+}
+
+// UnlockUser lifts a login lockout of the user's account
+func (uc *AuthUsecase) UnlockUser(ctx context.Context, userID string) error {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	return uc.loginThrottle.Unlock(ctx, user.Email)
+}
+
 func (uc *AuthUsecase) Logout(ctx context.Context, userID string, accessToken, refreshToken string) error {
 	// Get user info to check role
 	user, err := uc.userRepo.GetByID(ctx, userID)
@@ -100,27 +344,164 @@ func (uc *AuthUsecase) Logout(ctx context.Context, userID string, accessToken, r
 		}
 	}
 
-	// TODO: Implement token blacklisting
-	// For now, we'll just validate that the tokens exist and are from the right user
-
+	// Deny the tokens for the rest of their lifetime and end their refresh
+	// token family. Tokens of other users are ignored.
 	if accessToken != "" {
 		claims, err := uc.jwtUtil.ValidateAccessToken(accessToken)
 		if err == nil && claims.UserID == userID {
-			// TODO: Add token to blacklist in Redis
+			if err := uc.revokeToken(ctx, claims); err != nil {
+				return err
+			}
 		}
 	}
 
 	if refreshToken != "" {
 		claims, err := uc.jwtUtil.ValidateRefreshToken(refreshToken)
 		if err == nil && claims.UserID == userID {
-			// TODO: Add token to blacklist in Redis
+			if err := uc.revokeToken(ctx, claims); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
+// RevokeAllTokens revokes every access and refresh token issued to the user so
+// far. Tokens issued afterwards, e.g. by logging in again, stay valid.
+func (uc *AuthUsecase) RevokeAllTokens(ctx context.Context, userID string) error {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	if err := uc.tokenDenylist.RevokeAllForUser(ctx, user.ID, time.Now(), uc.jwtUtil.RefreshTokenDuration()); err != nil {
+		return err
+	}
+
+	sessions, err := uc.loginSessions.GetByUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if err := uc.loginSessions.Delete(ctx, user.ID, session.SessionID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetSessions lists the signed-in devices of the user, most recently active
+// first. currentSessionID marks the session the request was made with.
+func (uc *AuthUsecase) GetSessions(ctx context.Context, userID, currentSessionID string) ([]*domain.LoginSession, error) {
+	sessions, err := uc.loginSessions.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.SessionID == currentSessionID
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen.After(sessions[j].LastSeen)
+	})
+
+	return sessions, nil
+}
+
+// TerminateSession signs a device of the user out: its refresh token family is
+// revoked, which also rejects the access tokens it issued
+func (uc *AuthUsecase) TerminateSession(ctx context.Context, userID, sessionID string) error {
+	session, err := uc.loginSessions.Get(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if session == nil || session.UserID != userID {
+		return errors.New("session not found")
+	}
+
+	if err := uc.refreshFamilies.Revoke(ctx, sessionID, uc.jwtUtil.RefreshTokenDuration()); err != nil {
+		return err
+	}
+
+	return uc.loginSessions.Delete(ctx, userID, sessionID)
+}
+
+// TouchSession records activity on a login session
+func (uc *AuthUsecase) TouchSession(ctx context.Context, sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+	return uc.loginSessions.Touch(ctx, sessionID, time.Now())
+}
+
+// revokeToken denies the token and ends the login session it belongs to
+func (uc *AuthUsecase) revokeToken(ctx context.Context, claims *utils.JWTClaims) error {
+	if claims.FamilyID != "" {
+		if err := uc.refreshFamilies.Revoke(ctx, claims.FamilyID, uc.jwtUtil.RefreshTokenDuration()); err != nil {
+			return err
+		}
+
+		if err := uc.loginSessions.Delete(ctx, claims.UserID, claims.FamilyID); err != nil {
+			return err
+		}
+	}
+
+	if claims.ExpiresAt == nil {
+		return nil
+	}
+	return uc.tokenDenylist.Revoke(ctx, claims.ID, time.Until(claims.ExpiresAt.Time))
+}
+
+// checkRevoked rejects tokens that were revoked one by one or through a
+// revocation of all tokens of their user. Redis errors reject the token too.
+func (uc *AuthUsecase) checkRevoked(ctx context.Context, claims *utils.JWTClaims) error {
+	revoked, err := uc.tokenDenylist.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return err
+	}
+
+	if revoked {
+		return errors.New("token has been revoked")
+	}
+
+	revoked, err = uc.refreshFamilies.IsRevoked(ctx, claims.FamilyID)
+	if err != nil {
+		return err
+	}
+
+	if revoked {
+		return errors.New("token has been revoked")
+	}
+
+	revokedBefore, err := uc.tokenDenylist.RevokedBefore(ctx, claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Compared in milliseconds, so a login right after the revocation (e.g.
+	// after changing the password) gets tokens that are not revoked
+	if !revokedBefore.IsZero() && claims.IssuedAtMilli() <= revokedBefore.UnixMilli() {
+		return errors.New("token has been revoked")
+	}
+
+	return nil
+}
+
 func (uc *AuthUsecase) Register(ctx context.Context, req *domain.RegisterRequest) (*domain.User, error) {
+	// Supervisors only see their own department, so they need one
+	if req.Role == "supervisor" && req.DepartmentID == nil {
+		return nil, errors.New("supervisor must belong to a department")
+	}
+
 	// Check if user already exists
 	existingUser, err := uc.userRepo.GetByEmail(ctx, req.Email)
 	if err != nil {
@@ -169,6 +550,34 @@ func (uc *AuthUsecase) Register(ctx context.Context, req *domain.RegisterRequest
 	return user, nil
 }
 
+// ChangePassword sets a new password after checking the current one. Every
+// login of the user is revoked, including the one making the change.
+func (uc *AuthUsecase) ChangePassword(ctx context.Context, userID string, req *domain.ChangePasswordRequest) error {
+	if err := checkPasswordLength(req.NewPassword); err != nil {
+		return err
+	}
+
+	matches, err := passwordMatches(ctx, uc.userRepo, userID, req.CurrentPassword)
+	if err != nil {
+		return err
+	}
+
+	if !matches {
+		return errors.New("invalid current password")
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.userRepo.UpdatePassword(ctx, userID, hashedPassword); err != nil {
+		return err
+	}
+
+	return uc.RevokeAllTokens(ctx, userID)
+}
+
 func (uc *AuthUsecase) GetUserByID(ctx context.Context, userID string) (*domain.User, error) {
 	user, err := uc.userRepo.GetByID(ctx, userID)
 	if err != nil {
@@ -185,13 +594,25 @@ func (uc *AuthUsecase) GetUserByID(ctx context.Context, userID string) (*domain.
 	return user, nil
 }
 
-func (uc *AuthUsecase) RefreshToken(ctx context.Context, req *domain.RefreshTokenRequest) (*domain.RefreshTokenResponse, error) {
+// RefreshToken exchanges a refresh token for a new pair. Refresh tokens are
+// single-use: presenting one that was already exchanged means it leaked, so
+// the whole family, including the tokens handed out last, is revoked.
+func (uc *AuthUsecase) RefreshToken(ctx context.Context, req *domain.RefreshTokenRequest, clientIP, userAgent string) (*domain.RefreshTokenResponse, error) {
 	// Validate refresh token
 	claims, err := uc.jwtUtil.ValidateRefreshToken(req.RefreshToken)
 	if err != nil {
 		return nil, errors.New("invalid refresh token")
 	}
 
+	// Tokens from before rotation was introduced cannot be tracked
+	if claims.FamilyID == "" {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	if err := uc.checkRevoked(ctx, claims); err != nil {
+		return nil, err
+	}
+
 	// Check if user still exists and is active
 	user, err := uc.userRepo.GetByID(ctx, claims.UserID)
 	if err != nil {
@@ -206,16 +627,43 @@ func (uc *AuthUsecase) RefreshToken(ctx context.Context, req *domain.RefreshToke
 		return nil, errors.New("user account is inactive")
 	}
 
-	// Generate new token pair
+	// Generate new token pair in the same family
 	var departmentID *string
 	if user.DepartmentID.Valid {
 		departmentID = &user.DepartmentID.String
 	}
-	tokenPair, err := uc.jwtUtil.GenerateTokenPair(user.ID, user.Email, user.Role, departmentID)
+	tokenPair, err := uc.jwtUtil.GenerateTokenPair(user.ID, user.Email, user.Role, departmentID, claims.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated, reused, err := uc.refreshFamilies.Rotate(ctx, claims.FamilyID, claims.ID, tokenPair.RefreshTokenID, time.Until(tokenPair.RefreshExpiresAt))
 	if err != nil {
 		return nil, err
 	}
 
+	if reused {
+		log.Printf("Refresh token reuse detected for user %s (family %s, token %s) from %s (%s), revoking family",
+			claims.UserID, claims.FamilyID, claims.ID, clientIP, userAgent)
+
+		if err := uc.refreshFamilies.Revoke(ctx, claims.FamilyID, uc.jwtUtil.RefreshTokenDuration()); err != nil {
+			return nil, err
+		}
+
+		if err := uc.loginSessions.Delete(ctx, claims.UserID, claims.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("refresh token has already been used")
+	}
+
+	if !rotated {
+		return nil, errors.New("token has been revoked")
+	}
+
+	if err := uc.loginSessions.Extend(ctx, user.ID, claims.FamilyID, time.Until(tokenPair.RefreshExpiresAt)); err != nil {
+		return nil, err
+	}
+
 	return &domain.RefreshTokenResponse{
 		AccessToken:  tokenPair.AccessToken,
 		RefreshToken: tokenPair.RefreshToken,
@@ -224,27 +672,55 @@ func (uc *AuthUsecase) RefreshToken(ctx context.Context, req *domain.RefreshToke
 	}, nil
 }
 
-func (uc *AuthUsecase) ValidateToken(ctx context.Context, tokenString string) (*domain.User, error) {
+// ValidateToken checks an access token and returns its user together with the
+// ID of the login session the token belongs to
+func (uc *AuthUsecase) ValidateToken(ctx context.Context, tokenString string) (*domain.User, string, error) {
 	claims, err := uc.jwtUtil.ValidateAccessToken(tokenString)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	if err := uc.checkRevoked(ctx, claims); err != nil {
+		return nil, "", err
 	}
 
 	user, err := uc.userRepo.GetByID(ctx, claims.UserID)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	if user == nil {
-		return nil, errors.New("user not found")
+		return nil, "", errors.New("user not found")
 	}
 
 	if !user.IsActive {
-		return nil, errors.New("user account is inactive")
+		return nil, "", errors.New("user account is inactive")
 	}
 
 	// Hide password from response
 	user.Password = ""
 
-	return user, nil
+	return user, claims.FamilyID, nil
+}
+
+func checkPasswordLength(password string) error {
+	if len(password) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+	return nil
+}
+
+// passwordMatches checks a password against the stored hash of the user. The
+// user in the request context is loaded without the hash, so it is read again.
+func passwordMatches(ctx context.Context, userRepo domain.UserRepository, userID, password string) (bool, error) {
+	user, err := userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if user == nil {
+		return false, errors.New("user not found")
+	}
+
+	return utils.CheckPasswordHash(password, user.Password), nil
 }
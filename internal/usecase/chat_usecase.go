@@ -4,19 +4,50 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	stdlog "log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/novianakbar/livechat-be/internal/domain"
+	"github.com/novianakbar/livechat-be/pkg/utils"
 )
 
+// AgentAssigner selects the agent that should handle a session
+type AgentAssigner interface {
+	SelectAgent(ctx context.Context, session *domain.ChatSession) (*domain.User, error)
+	CanTakeSession(ctx context.Context, agent *domain.User) (bool, error)
+}
+
+// SessionQueue keeps waiting sessions ordered for assignment
+type SessionQueue interface {
+	Enqueue(ctx context.Context, session *domain.ChatSession) error
+	Remove(ctx context.Context, sessionID string) error
+	NextForAgent(ctx context.Context, agent *domain.User) (string, error)
+	GetQueueStatus(ctx context.Context, session *domain.ChatSession) (*domain.QueueStatusResponse, error)
+}
+
+// PermissionChecker tells whether users of a role have a permission
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, role, permission string) (bool, error)
+}
+
 type ChatUsecase struct {
-	sessionRepo  domain.ChatSessionRepository
-	messageRepo  domain.ChatMessageRepository
-	userRepo     domain.UserRepository
-	logRepo      domain.ChatLogRepository
-	chatUserRepo domain.ChatUserRepository           // Added for OSS support
-	contactRepo  domain.ChatSessionContactRepository // Added for OSS support
+	sessionRepo      domain.ChatSessionRepository
+	messageRepo      domain.ChatMessageRepository
+	userRepo         domain.UserRepository
+	logRepo          domain.ChatLogRepository
+	chatUserRepo     domain.ChatUserRepository           // Added for OSS support
+	contactRepo      domain.ChatSessionContactRepository // Added for OSS support
+	departmentRepo   domain.DepartmentRepository
+	topicMappingRepo domain.TopicDepartmentMappingRepository
+	ratingRepo       domain.SessionRatingRepository
+	agentAssigner    AgentAssigner
+	sessionQueue     SessionQueue
+	permissions      PermissionChecker
+	jwtUtil          *utils.JWTUtil
+	customerTokenTTL time.Duration
 }
 
 func NewChatUsecase(
@@ -26,14 +57,30 @@ func NewChatUsecase(
 	logRepo domain.ChatLogRepository,
 	chatUserRepo domain.ChatUserRepository, // Added for OSS support
 	contactRepo domain.ChatSessionContactRepository, // Added for OSS support
+	departmentRepo domain.DepartmentRepository,
+	topicMappingRepo domain.TopicDepartmentMappingRepository,
+	ratingRepo domain.SessionRatingRepository,
+	agentAssigner AgentAssigner,
+	sessionQueue SessionQueue,
+	permissions PermissionChecker,
+	jwtUtil *utils.JWTUtil,
+	customerTokenTTL time.Duration,
 ) *ChatUsecase {
 	return &ChatUsecase{
-		sessionRepo:  sessionRepo,
-		messageRepo:  messageRepo,
-		userRepo:     userRepo,
-		logRepo:      logRepo,
-		chatUserRepo: chatUserRepo,
-		contactRepo:  contactRepo,
+		sessionRepo:      sessionRepo,
+		messageRepo:      messageRepo,
+		userRepo:         userRepo,
+		logRepo:          logRepo,
+		chatUserRepo:     chatUserRepo,
+		contactRepo:      contactRepo,
+		departmentRepo:   departmentRepo,
+		topicMappingRepo: topicMappingRepo,
+		ratingRepo:       ratingRepo,
+		agentAssigner:    agentAssigner,
+		sessionQueue:     sessionQueue,
+		permissions:      permissions,
+		jwtUtil:          jwtUtil,
+		customerTokenTTL: customerTokenTTL,
 	}
 }
 
@@ -136,7 +183,9 @@ func (uc *ChatUsecase) SendMessage(ctx context.Context, req *domain.SendMessageR
 	}, nil
 }
 
-func (uc *ChatUsecase) AssignAgent(ctx context.Context, req *domain.AssignAgentRequest) error {
+// AssignAgent assigns a session to an agent. With a department scope both the
+// session and the agent must belong to that department.
+func (uc *ChatUsecase) AssignAgent(ctx context.Context, req *domain.AssignAgentRequest, departmentScope *uuid.UUID) error {
 	// Validate session exists
 	session, err := uc.sessionRepo.GetByID(ctx, req.SessionID)
 	if err != nil {
@@ -147,6 +196,14 @@ func (uc *ChatUsecase) AssignAgent(ctx context.Context, req *domain.AssignAgentR
 		return errors.New("chat session not found")
 	}
 
+	if session.Status == "closed" {
+		return errors.New("cannot assign closed session")
+	}
+
+	if !inDepartmentScope(session.DepartmentID, departmentScope) {
+		return errors.New("session is outside your department")
+	}
+
 	// Validate agent exists
 	agent, err := uc.userRepo.GetByID(ctx, req.AgentID.String())
 	if err != nil {
@@ -161,6 +218,10 @@ func (uc *ChatUsecase) AssignAgent(ctx context.Context, req *domain.AssignAgentR
 		return errors.New("user is not an agent")
 	}
 
+	if !inDepartmentScope(agent.DepartmentID, departmentScope) {
+		return errors.New("agent is outside your department")
+	}
+
 	// Update session
 	session.AgentID = sql.NullString{
 		String: req.AgentID.String(),
@@ -168,11 +229,18 @@ func (uc *ChatUsecase) AssignAgent(ctx context.Context, req *domain.AssignAgentR
 	}
 	session.DepartmentID = agent.DepartmentID
 	session.UpdatedAt = time.Now()
+	// Clear preloaded associations so Save does not write them back
+	session.Agent = nil
+	session.Department = nil
 
 	if err := uc.sessionRepo.Update(ctx, session); err != nil {
 		return err
 	}
 
+	if err := uc.sessionQueue.Remove(ctx, session.ID); err != nil {
+		return err
+	}
+
 	// Log assignment
 	uuidV7Log2, _ := uuid.NewV7()
 	log := &domain.ChatLog{
@@ -197,7 +265,319 @@ func (uc *ChatUsecase) AssignAgent(ctx context.Context, req *domain.AssignAgentR
 	return nil
 }
 
-func (uc *ChatUsecase) CloseSession(ctx context.Context, sessionID uuid.UUID, reason string, userID *uuid.UUID) error {
+// TransferSession hands a session over to another agent or department.
+// When only a department is given the session goes back to waiting so any
+// agent of that department can pick it up. A system message is posted into
+// the conversation and returned so the caller can broadcast it. With a
+// department scope the session can only move within that department.
+func (uc *ChatUsecase) TransferSession(ctx context.Context, req *domain.TransferChatRequest, userID *uuid.UUID, departmentScope *uuid.UUID) (*domain.ChatMessage, error) {
+	if req.NewAgentID == nil && req.NewDepartmentID == nil {
+		return nil, errors.New("either new agent or new department is required")
+	}
+
+	// Validate session exists
+	session, err := uc.sessionRepo.GetByID(ctx, req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session == nil {
+		return nil, errors.New("chat session not found")
+	}
+
+	if session.Status == "closed" {
+		return nil, errors.New("cannot transfer closed session")
+	}
+
+	if !inDepartmentScope(session.DepartmentID, departmentScope) {
+		return nil, errors.New("session is outside your department")
+	}
+
+	if req.NewDepartmentID != nil && departmentScope != nil && *req.NewDepartmentID != *departmentScope {
+		return nil, errors.New("cannot transfer outside your department")
+	}
+
+	previousAgentID := session.AgentID
+
+	var details, notice string
+	if req.NewAgentID != nil {
+		agent, err := uc.userRepo.GetByID(ctx, req.NewAgentID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		if agent == nil {
+			return nil, errors.New("agent not found")
+		}
+
+		if agent.Role != "agent" {
+			return nil, errors.New("user is not an agent")
+		}
+
+		if !agent.IsActive {
+			return nil, errors.New("agent is inactive")
+		}
+
+		if !inDepartmentScope(agent.DepartmentID, departmentScope) {
+			return nil, errors.New("agent is outside your department")
+		}
+
+		if session.AgentID.Valid && session.AgentID.String == agent.ID {
+			return nil, errors.New("session is already assigned to this agent")
+		}
+
+		if req.NewDepartmentID != nil && (!agent.DepartmentID.Valid || agent.DepartmentID.String != req.NewDepartmentID.String()) {
+			return nil, errors.New("agent does not belong to the target department")
+		}
+
+		session.AgentID = sql.NullString{
+			String: agent.ID,
+			Valid:  true,
+		}
+		session.DepartmentID = agent.DepartmentID
+
+		details = "Session transferred to agent " + agent.Name
+		notice = "Your chat has been transferred to " + agent.Name + "."
+	} else {
+		session.AgentID = sql.NullString{Valid: false}
+		session.DepartmentID = sql.NullString{
+			String: req.NewDepartmentID.String(),
+			Valid:  true,
+		}
+		session.Status = "waiting"
+
+		details = "Session transferred to department " + req.NewDepartmentID.String()
+		notice = "Your chat has been transferred to another department. Please wait for an available agent."
+	}
+
+	session.UpdatedAt = time.Now()
+	// Clear preloaded associations so Save does not write them back
+	session.Agent = nil
+	session.Department = nil
+
+	if err := uc.sessionRepo.Update(ctx, session); err != nil {
+		return nil, err
+	}
+
+	if session.Status == "waiting" && !session.AgentID.Valid {
+		err = uc.sessionQueue.Enqueue(ctx, session)
+	} else {
+		err = uc.sessionQueue.Remove(ctx, session.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Reason != "" {
+		details += ": " + req.Reason
+	}
+
+	// Log transfer
+	uuidV7Transfer, _ := uuid.NewV7()
+	log := &domain.ChatLog{
+		ID:        uuidV7Transfer.String(),
+		SessionID: session.ID,
+		Action:    "transferred",
+		Details: sql.NullString{
+			String: details,
+			Valid:  true,
+		},
+		UserID: func() sql.NullString {
+			if userID != nil {
+				return sql.NullString{String: userID.String(), Valid: true}
+			}
+			return sql.NullString{Valid: false}
+		}(),
+		CreatedAt: time.Now(),
+	}
+
+	if err := uc.logRepo.Create(ctx, log); err != nil {
+		return nil, err
+	}
+
+	message, err := uc.createSystemMessage(ctx, session.ID, notice)
+	if err != nil {
+		return nil, err
+	}
+
+	// The previous agent has a free slot now
+	if previousAgentID.Valid {
+		uc.assignNextWaiting(ctx, previousAgentID.String)
+	}
+
+	return message, nil
+}
+
+// ReleaseAgentSessions puts the open sessions of an agent who can no longer
+// handle them back into the waiting queue of their department and tries to
+// hand each one to another agent right away. The agent is already gone by
+// then, so a session that fails is logged and the others are still released.
+func (uc *ChatUsecase) ReleaseAgentSessions(ctx context.Context, agentID uuid.UUID, reason string) error {
+	sessions, err := uc.sessionRepo.GetByAgentID(ctx, agentID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.Status == "closed" {
+			continue
+		}
+
+		if err := uc.releaseSession(ctx, session, reason); err != nil {
+			stdlog.Printf("Failed to release session %s of agent %s: %v", session.ID, agentID, err)
+		}
+	}
+
+	return nil
+}
+
+func (uc *ChatUsecase) releaseSession(ctx context.Context, session *domain.ChatSession, reason string) error {
+	session.AgentID = sql.NullString{Valid: false}
+	session.Status = "waiting"
+	session.UpdatedAt = time.Now()
+	// Clear preloaded associations so Save does not write them back
+	session.Agent = nil
+	session.Department = nil
+	session.Contact = nil
+
+	if err := uc.sessionRepo.Update(ctx, session); err != nil {
+		return err
+	}
+
+	if err := uc.sessionQueue.Enqueue(ctx, session); err != nil {
+		return err
+	}
+
+	uuidV7, _ := uuid.NewV7()
+	entry := &domain.ChatLog{
+		ID:        uuidV7.String(),
+		SessionID: session.ID,
+		Action:    "transferred",
+		Details: sql.NullString{
+			String: "Session returned to queue: " + reason,
+			Valid:  true,
+		},
+		CreatedAt: time.Now(),
+	}
+
+	if err := uc.logRepo.Create(ctx, entry); err != nil {
+		return err
+	}
+
+	if _, err := uc.createSystemMessage(ctx, session.ID, "Your agent is no longer available. Please wait for another agent."); err != nil {
+		return err
+	}
+
+	// Failures leave the session queued for the assignment worker
+	sessionID, _ := uuid.Parse(session.ID)
+	_ = uc.AutoAssignAgent(ctx, sessionID)
+
+	return nil
+}
+
+// AuthorizeSession checks that a staff user may perform the action on the
+// session. Agents may act on the sessions assigned to them, and may take
+// unassigned sessions of their own department. Users with the
+// "sessions:override" permission may act on any other session within their
+// department scope. For an override the access_override log entry is
+// returned; record it with RecordAccessOverride once the action succeeded.
+func (uc *ChatUsecase) AuthorizeSession(ctx context.Context, sessionID uuid.UUID, actor *domain.User, action string) (*domain.ChatLog, error) {
+	session, err := uc.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session == nil {
+		return nil, errors.New("chat session not found")
+	}
+
+	if session.AgentID.Valid && session.AgentID.String == actor.ID {
+		return nil, nil
+	}
+
+	if action == "assign" && !session.AgentID.Valid &&
+		(!session.DepartmentID.Valid || session.DepartmentID == actor.DepartmentID) {
+		return nil, nil
+	}
+
+	canOverride, err := uc.permissions.HasPermission(ctx, actor.Role, "sessions:override")
+	if err != nil {
+		return nil, err
+	}
+
+	if !canOverride {
+		return nil, errors.New("you are not assigned to this session")
+	}
+
+	allDepartments, err := uc.permissions.HasPermission(ctx, actor.Role, "departments:all")
+	if err != nil {
+		return nil, err
+	}
+
+	if !allDepartments && (!actor.DepartmentID.Valid || session.DepartmentID != actor.DepartmentID) {
+		return nil, errors.New("session is outside your department")
+	}
+
+	assignee := "nobody"
+	if session.AgentID.Valid {
+		assignee = "agent " + session.AgentID.String
+	}
+
+	uuidV7, _ := uuid.NewV7()
+	entry := &domain.ChatLog{
+		ID:        uuidV7.String(),
+		SessionID: session.ID,
+		Action:    "access_override",
+		Details: sql.NullString{
+			String: fmt.Sprintf("%s %s used %s on session assigned to %s", actor.Role, actor.Name, action, assignee),
+			Valid:  true,
+		},
+		UserID: sql.NullString{
+			String: actor.ID,
+			Valid:  true,
+		},
+		CreatedAt: time.Now(),
+	}
+
+	return entry, nil
+}
+
+// RecordAccessOverride stores the access_override entry returned by
+// AuthorizeSession
+func (uc *ChatUsecase) RecordAccessOverride(ctx context.Context, entry *domain.ChatLog) error {
+	entry.CreatedAt = time.Now()
+	return uc.logRepo.Create(ctx, entry)
+}
+
+// inDepartmentScope reports whether a department lies within the scope. A nil
+// scope covers every department.
+func inDepartmentScope(departmentID sql.NullString, scope *uuid.UUID) bool {
+	return scope == nil || (departmentID.Valid && departmentID.String == scope.String())
+}
+
+// createSystemMessage stores a system message that is visible to both sides of the conversation
+func (uc *ChatUsecase) createSystemMessage(ctx context.Context, sessionID string, text string) (*domain.ChatMessage, error) {
+	uuidV7, _ := uuid.NewV7()
+	message := &domain.ChatMessage{
+		ID:          uuidV7.String(),
+		SessionID:   sessionID,
+		SenderType:  "system",
+		Message:     text,
+		MessageType: "system",
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if err := uc.messageRepo.Create(ctx, message); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// CloseSession closes a session. With a department scope the session must
+// belong to that department.
+func (uc *ChatUsecase) CloseSession(ctx context.Context, sessionID uuid.UUID, reason string, userID *uuid.UUID, departmentScope *uuid.UUID) error {
 	// Validate session exists
 	session, err := uc.sessionRepo.GetByID(ctx, sessionID)
 	if err != nil {
@@ -208,6 +588,10 @@ func (uc *ChatUsecase) CloseSession(ctx context.Context, sessionID uuid.UUID, re
 		return errors.New("chat session not found")
 	}
 
+	if !inDepartmentScope(session.DepartmentID, departmentScope) {
+		return errors.New("session is outside your department")
+	}
+
 	if session.Status == "closed" {
 		return errors.New("session is already closed")
 	}
@@ -217,6 +601,10 @@ func (uc *ChatUsecase) CloseSession(ctx context.Context, sessionID uuid.UUID, re
 		return err
 	}
 
+	if err := uc.sessionQueue.Remove(ctx, session.ID); err != nil {
+		return err
+	}
+
 	// Log closure
 	uuidV7Close, _ := uuid.NewV7()
 	log := &domain.ChatLog{
@@ -240,6 +628,79 @@ func (uc *ChatUsecase) CloseSession(ctx context.Context, sessionID uuid.UUID, re
 		return err
 	}
 
+	// The agent has a free slot now, hand them the next waiting session
+	if session.AgentID.Valid {
+		uc.assignNextWaiting(ctx, session.AgentID.String)
+	}
+
+	return nil
+}
+
+// RateSession stores the customer satisfaction rating of a closed session.
+// A session can only be rated once. relayedBy is the staff user who entered
+// the rating on the customer's behalf, nil when the customer rated directly.
+func (uc *ChatUsecase) RateSession(ctx context.Context, sessionID uuid.UUID, req *domain.RateSessionRequest, relayedBy *uuid.UUID) (*domain.SessionRating, error) {
+	if err := ValidateRating(req.Rating); err != nil {
+		return nil, err
+	}
+
+	session, err := uc.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session == nil {
+		return nil, errors.New("chat session not found")
+	}
+
+	if session.Status != "closed" {
+		return nil, errors.New("session is not closed yet")
+	}
+
+	existing, err := uc.ratingRepo.GetBySessionID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		return nil, errors.New("session has already been rated")
+	}
+
+	uuidV7Rating, _ := uuid.NewV7()
+	rating := &domain.SessionRating{
+		ID:        uuidV7Rating.String(),
+		SessionID: session.ID,
+		AgentID:   session.AgentID,
+		Rating:    req.Rating,
+		Feedback: sql.NullString{
+			String: req.Feedback,
+			Valid:  req.Feedback != "",
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if relayedBy != nil {
+		rating.RelayedBy = sql.NullString{String: relayedBy.String(), Valid: true}
+	}
+
+	// A concurrent rating can still land between the check above and the insert
+	created, err := uc.ratingRepo.Create(ctx, rating)
+	if err != nil {
+		return nil, err
+	}
+
+	if !created {
+		return nil, errors.New("session has already been rated")
+	}
+
+	return rating, nil
+}
+
+// ValidateRating checks that a CSAT rating is on the 1 to 5 scale
+func ValidateRating(rating int) error {
+	if rating < 1 || rating > 5 {
+		return errors.New("rating must be between 1 and 5")
+	}
 	return nil
 }
 
@@ -252,8 +713,8 @@ func (uc *ChatUsecase) GetSessionMessages(ctx context.Context, sessionID uuid.UU
 	return messages, nil
 }
 
-func (uc *ChatUsecase) GetWaitingSessions(ctx context.Context) ([]*domain.ChatSession, error) {
-	sessions, err := uc.sessionRepo.GetWaitingSessions(ctx)
+func (uc *ChatUsecase) GetWaitingSessions(ctx context.Context, departmentID *uuid.UUID) ([]*domain.ChatSession, error) {
+	sessions, err := uc.sessionRepo.GetWaitingSessions(ctx, departmentID)
 	if err != nil {
 		return nil, err
 	}
@@ -261,8 +722,8 @@ func (uc *ChatUsecase) GetWaitingSessions(ctx context.Context) ([]*domain.ChatSe
 	return sessions, nil
 }
 
-func (uc *ChatUsecase) GetActiveSessions(ctx context.Context) ([]*domain.ChatSession, error) {
-	sessions, err := uc.sessionRepo.GetActiveSessions(ctx)
+func (uc *ChatUsecase) GetActiveSessions(ctx context.Context, departmentID *uuid.UUID) ([]*domain.ChatSession, error) {
+	sessions, err := uc.sessionRepo.GetActiveSessions(ctx, departmentID)
 	if err != nil {
 		return nil, err
 	}
@@ -280,18 +741,14 @@ func (uc *ChatUsecase) GetAgentSessions(ctx context.Context, agentID uuid.UUID)
 }
 
 func (uc *ChatUsecase) GetAgentSessionsWithPagination(ctx context.Context, agentID uuid.UUID, page, limit int, status string) ([]*domain.ChatSession, int, error) {
-	offset := (page - 1) * limit
-	sessions, err := uc.sessionRepo.GetWithPagination(ctx, offset, limit, status, &agentID, nil)
-	if err != nil {
-		return nil, 0, err
-	}
-
-	total, err := uc.sessionRepo.Count(ctx, status, &agentID, nil)
-	if err != nil {
-		return nil, 0, err
-	}
-
-	return sessions, total, nil
+	return uc.GetSessions(ctx, &domain.SessionListRequest{
+		Status:  status,
+		AgentID: &agentID,
+		PaginationRequest: domain.PaginationRequest{
+			Page:     page,
+			PageSize: limit,
+		},
+	})
 }
 
 func (uc *ChatUsecase) GetChatUserSessions(ctx context.Context, chatUserID uuid.UUID) ([]*domain.ChatSession, error) {
@@ -303,14 +760,16 @@ func (uc *ChatUsecase) GetChatUserSessions(ctx context.Context, chatUserID uuid.
 	return sessions, nil
 }
 
-func (uc *ChatUsecase) GetSessions(ctx context.Context, page, limit int, status string, agentID, departmentID *uuid.UUID) ([]*domain.ChatSession, int, error) {
-	offset := (page - 1) * limit
-	sessions, err := uc.sessionRepo.GetWithPagination(ctx, offset, limit, status, agentID, departmentID)
+// GetSessions returns a page of the sessions matching the filter and the
+// total number of matches
+func (uc *ChatUsecase) GetSessions(ctx context.Context, filter *domain.SessionListRequest) ([]*domain.ChatSession, int, error) {
+	offset := (filter.Page - 1) * filter.PageSize
+	sessions, err := uc.sessionRepo.GetWithPagination(ctx, offset, filter.PageSize, filter)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	total, err := uc.sessionRepo.Count(ctx, status, agentID, departmentID)
+	total, err := uc.sessionRepo.CountWithFilter(ctx, filter)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -318,6 +777,17 @@ func (uc *ChatUsecase) GetSessions(ctx context.Context, page, limit int, status
 	return sessions, total, nil
 }
 
+// SearchMessages runs a full-text search over message bodies, limited to one
+// department when DepartmentID is set
+func (uc *ChatUsecase) SearchMessages(ctx context.Context, req *domain.MessageSearchRequest) ([]domain.MessageSearchResult, int, error) {
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" {
+		return nil, 0, errors.New("search query is required")
+	}
+
+	return uc.messageRepo.Search(ctx, req)
+}
+
 func (uc *ChatUsecase) GetSession(ctx context.Context, sessionID uuid.UUID) (*domain.ChatSession, error) {
 	session, err := uc.sessionRepo.GetByID(ctx, sessionID)
 	if err != nil {
@@ -329,40 +799,55 @@ func (uc *ChatUsecase) GetSession(ctx context.Context, sessionID uuid.UUID) (*do
 
 // AutoAssignAgent automatically assigns an available agent to a session
 func (uc *ChatUsecase) AutoAssignAgent(ctx context.Context, sessionID uuid.UUID) error {
-	// Get available agents (simple round-robin for now)
-	agents, err := uc.userRepo.GetAvailableAgents(ctx, nil) // nil for any department
+	session, err := uc.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if session == nil {
+		return errors.New("chat session not found")
+	}
+
+	// Sessions closed or assigned since they were queued need no agent
+	if session.Status != "waiting" || session.AgentID.Valid {
+		return uc.sessionQueue.Remove(ctx, session.ID)
+	}
+
+	// Pick an agent based on presence, load and the configured strategy
+	agent, err := uc.agentAssigner.SelectAgent(ctx, session)
 	if err != nil {
 		return err
 	}
 
-	if len(agents) == 0 {
+	if agent == nil {
 		// No agents available, session stays in waiting status
 		return nil
 	}
 
-	// Simple assignment - assign to the first available agent
-	// In production, you might want more sophisticated load balancing
-	agentID := agents[0].ID
+	return uc.autoAssign(ctx, session, agent.ID)
+}
 
-	// Update session
-	session, err := uc.sessionRepo.GetByID(ctx, sessionID)
+// autoAssign activates the session for the agent, takes it out of the
+// waiting queue and logs the assignment. The session is only assigned if it is
+// still waiting without an agent; otherwise it was handled elsewhere in the
+// meantime and is only removed from the queue.
+func (uc *ChatUsecase) autoAssign(ctx context.Context, session *domain.ChatSession, agentID string) error {
+	sessionUUID, err := uuid.Parse(session.ID)
 	if err != nil {
 		return err
 	}
 
-	if session == nil {
-		return errors.New("chat session not found")
+	assigned, err := uc.sessionRepo.AssignIfWaiting(ctx, sessionUUID, agentID)
+	if err != nil {
+		return err
 	}
 
-	session.AgentID = sql.NullString{
-		String: agentID,
-		Valid:  true,
+	if err := uc.sessionQueue.Remove(ctx, session.ID); err != nil {
+		return err
 	}
-	session.Status = "active"
-	session.UpdatedAt = time.Now()
 
-	if err := uc.sessionRepo.Update(ctx, session); err != nil {
-		return err
+	if !assigned {
+		return nil
 	}
 
 	// Log assignment
@@ -389,33 +874,116 @@ func (uc *ChatUsecase) AutoAssignAgent(ctx context.Context, sessionID uuid.UUID)
 	return nil
 }
 
+// assignNextWaiting gives the head of the waiting queue to an agent that just
+// freed up a slot. Failures are not reported to the caller; the session simply
+// stays queued.
+func (uc *ChatUsecase) assignNextWaiting(ctx context.Context, agentID string) {
+	agent, err := uc.userRepo.GetByID(ctx, agentID)
+	if err != nil || agent == nil || agent.Role != "agent" || !agent.IsActive {
+		return
+	}
+
+	if available, err := uc.agentAssigner.CanTakeSession(ctx, agent); err != nil || !available {
+		return
+	}
+
+	nextID, err := uc.sessionQueue.NextForAgent(ctx, agent)
+	if err != nil || nextID == "" {
+		return
+	}
+
+	sessionUUID, err := uuid.Parse(nextID)
+	if err != nil {
+		uc.sessionQueue.Remove(ctx, nextID)
+		return
+	}
+
+	session, err := uc.sessionRepo.GetByID(ctx, sessionUUID)
+	if err != nil {
+		return
+	}
+
+	// Drop stale entries whose session was handled elsewhere
+	if session == nil || session.Status != "waiting" || session.AgentID.Valid {
+		uc.sessionQueue.Remove(ctx, nextID)
+		return
+	}
+
+	uc.autoAssign(ctx, session, agent.ID)
+}
+
+// GetIdleSessions lists sessions in the given status without activity since idleSince
+func (uc *ChatUsecase) GetIdleSessions(ctx context.Context, status string, idleSince time.Time) ([]*domain.IdleSession, error) {
+	return uc.sessionRepo.GetIdleSessions(ctx, status, idleSince)
+}
+
+// WarnIdleSession posts an inactivity warning into the conversation and logs it
+func (uc *ChatUsecase) WarnIdleSession(ctx context.Context, sessionID uuid.UUID, closeIn time.Duration) (*domain.ChatMessage, error) {
+	minutes := int(closeIn.Round(time.Minute).Minutes())
+	if minutes < 1 {
+		minutes = 1
+	}
+
+	message, err := uc.createSystemMessage(ctx, sessionID.String(),
+		fmt.Sprintf("This chat will be closed in %d minute(s) due to inactivity. Send a message to keep it open.", minutes))
+	if err != nil {
+		return nil, err
+	}
+
+	uuidV7Warning, _ := uuid.NewV7()
+	log := &domain.ChatLog{
+		ID:        uuidV7Warning.String(),
+		SessionID: sessionID.String(),
+		Action:    "idle_warning",
+		Details: sql.NullString{
+			String: fmt.Sprintf("Session idle, closing in %d minute(s)", minutes),
+			Valid:  true,
+		},
+		CreatedAt: time.Now(),
+	}
+
+	if err := uc.logRepo.Create(ctx, log); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// CloseIdleSession closes a session that timed out and tells the participants why
+func (uc *ChatUsecase) CloseIdleSession(ctx context.Context, sessionID uuid.UUID) (*domain.ChatMessage, error) {
+	if err := uc.CloseSession(ctx, sessionID, "idle_timeout", nil, nil); err != nil {
+		return nil, err
+	}
+
+	return uc.createSystemMessage(ctx, sessionID.String(), "This chat has been closed due to inactivity.")
+}
+
+// GetQueueStatus returns the queue position and estimated wait of a session
+func (uc *ChatUsecase) GetQueueStatus(ctx context.Context, sessionID uuid.UUID) (*domain.QueueStatusResponse, error) {
+	session, err := uc.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session == nil {
+		return nil, errors.New("chat session not found")
+	}
+
+	return uc.sessionQueue.GetQueueStatus(ctx, session)
+}
+
 func (uc *ChatUsecase) GetMessageByID(ctx context.Context, messageID uuid.UUID) (*domain.ChatMessage, error) {
 	return uc.messageRepo.GetByID(ctx, messageID)
 }
 
 // StartOSSChat handles OSS-specific chat starting logic
 func (uc *ChatUsecase) StartOSSChat(ctx context.Context, req *domain.StartChatRequest, ipAddress string) (*domain.StartChatResponse, error) {
-	var chatUser *domain.ChatUser
-	var err error
-
-	// Determine if user is anonymous or logged-in
-	if req.BrowserUUID != nil {
-		// Try to get existing user by browser UUID
-		chatUser, err = uc.chatUserRepo.GetByBrowserUUID(ctx, *req.BrowserUUID)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	if chatUser == nil && req.OSSUserID != nil && req.Email != nil {
-		// Try to get existing user by OSS user ID
-		chatUser, err = uc.chatUserRepo.GetByOSSUserID(ctx, *req.OSSUserID)
-		if err != nil {
-			return nil, err
-		}
+	chatUser, err := uc.ownedChatUser(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create new user if not found
+	// Create new user if the caller did not prove to own one
 	if chatUser == nil {
 		uuidV7ChatUser, _ := uuid.NewV7()
 		chatUser = &domain.ChatUser{
@@ -432,8 +1000,12 @@ func (uc *ChatUsecase) StartOSSChat(ctx context.Context, req *domain.StartChatRe
 		}
 
 		if req.BrowserUUID != nil {
+			browserUUID, err := uc.unusedBrowserUUID(ctx, *req.BrowserUUID)
+			if err != nil {
+				return nil, err
+			}
 			chatUser.BrowserUUID = sql.NullString{
-				String: req.BrowserUUID.String(),
+				String: browserUUID.String(),
 				Valid:  true,
 			}
 			chatUser.IsAnonymous = true
@@ -456,17 +1028,24 @@ func (uc *ChatUsecase) StartOSSChat(ctx context.Context, req *domain.StartChatRe
 		}
 	}
 
+	// Route the session to a department so only its agents pick it up
+	departmentID, err := uc.resolveDepartment(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create chat session
 	uuidV7Session, _ := uuid.NewV7()
 	session := &domain.ChatSession{
-		ID:         uuidV7Session.String(),
-		ChatUserID: chatUser.ID,
-		Topic:      req.Topic,
-		Status:     "waiting",
-		Priority:   req.Priority,
-		StartedAt:  time.Now(),
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		ID:           uuidV7Session.String(),
+		ChatUserID:   chatUser.ID,
+		DepartmentID: departmentID,
+		Topic:        req.Topic,
+		Status:       "waiting",
+		Priority:     req.Priority,
+		StartedAt:    time.Now(),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
 	}
 
 	if req.Priority == "" {
@@ -494,6 +1073,10 @@ func (uc *ChatUsecase) StartOSSChat(ctx context.Context, req *domain.StartChatRe
 		return nil, err
 	}
 
+	// Queue the session first; a successful auto-assignment removes it again.
+	// Failures are tolerated since the queue status endpoint re-enqueues it.
+	uc.sessionQueue.Enqueue(ctx, session)
+
 	// Try to auto-assign an agent
 	sessionUUID, _ := uuid.Parse(session.ID)
 	if err := uc.AutoAssignAgent(ctx, sessionUUID); err != nil {
@@ -517,15 +1100,180 @@ func (uc *ChatUsecase) StartOSSChat(ctx context.Context, req *domain.StartChatRe
 	sessionUUIDForDTO, _ := uuid.Parse(session.ID)
 	chatUserUUIDForDTO, _ := uuid.Parse(chatUser.ID)
 
-	return &domain.StartChatResponse{
+	response := &domain.StartChatResponse{
 		SessionID:       sessionUUIDForDTO,
 		ChatUserID:      chatUserUUIDForDTO,
 		Status:          session.Status,
 		Message:         "Chat session started successfully",
 		RequiresContact: requiresContact,
+	}
+
+	if session.DepartmentID.Valid {
+		if departmentUUID, err := uuid.Parse(session.DepartmentID.String); err == nil {
+			response.DepartmentID = &departmentUUID
+		}
+	}
+
+	response.CustomerToken, response.CustomerTokenExpiresAt, err = uc.jwtUtil.GenerateCustomerToken(chatUser.ID, session.ID, uc.customerTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// ownedChatUser returns the chat user of the customer token sent along with
+// the start request, if it has the browser UUID or OSS user ID of the request.
+// Both are supplied by the client, so without the token they do not prove the
+// chat user, and its history, belongs to the caller.
+func (uc *ChatUsecase) ownedChatUser(ctx context.Context, req *domain.StartChatRequest) (*domain.ChatUser, error) {
+	if req.Customer == nil {
+		return nil, nil
+	}
+
+	chatUser, err := uc.chatUserRepo.GetByID(ctx, req.Customer.ChatUserID)
+	if err != nil || chatUser == nil {
+		return nil, err
+	}
+
+	if req.BrowserUUID != nil && chatUser.BrowserUUID.Valid && chatUser.BrowserUUID.String == req.BrowserUUID.String() {
+		return chatUser, nil
+	}
+
+	if req.OSSUserID != nil && req.Email != nil && chatUser.OSSUserID.Valid && chatUser.OSSUserID.String == *req.OSSUserID {
+		return chatUser, nil
+	}
+
+	return nil, nil
+}
+
+// unusedBrowserUUID returns the browser UUID, or a new one when another chat
+// user already has it, since browser UUIDs are unique
+func (uc *ChatUsecase) unusedBrowserUUID(ctx context.Context, browserUUID uuid.UUID) (uuid.UUID, error) {
+	existing, err := uc.chatUserRepo.GetByBrowserUUID(ctx, browserUUID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if existing == nil {
+		return browserUUID, nil
+	}
+
+	return uuid.NewV7()
+}
+
+// ValidateCustomerToken checks a chat widget token and returns the chat user
+// and session it was issued for
+func (uc *ChatUsecase) ValidateCustomerToken(ctx context.Context, tokenString string) (*domain.CustomerIdentity, error) {
+	claims, err := uc.jwtUtil.ValidateCustomerToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	chatUserID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, errors.New("invalid token claims")
+	}
+
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return nil, errors.New("invalid token claims")
+	}
+
+	session, err := uc.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session == nil || session.ChatUserID != claims.UserID {
+		return nil, errors.New("chat session not found")
+	}
+
+	return &domain.CustomerIdentity{
+		ChatUserID: chatUserID,
+		SessionID:  sessionID,
+	}, nil
+}
+
+// RefreshCustomerToken issues a new widget token for the same chat. Tokens of
+// closed sessions are not renewed.
+func (uc *ChatUsecase) RefreshCustomerToken(ctx context.Context, customer *domain.CustomerIdentity) (*domain.CustomerTokenResponse, error) {
+	session, err := uc.sessionRepo.GetByID(ctx, customer.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session == nil {
+		return nil, errors.New("chat session not found")
+	}
+
+	if session.Status == "closed" {
+		return nil, errors.New("chat session is closed")
+	}
+
+	token, expiresAt, err := uc.jwtUtil.GenerateCustomerToken(customer.ChatUserID.String(), customer.SessionID.String(), uc.customerTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.CustomerTokenResponse{
+		CustomerToken: token,
+		ExpiresAt:     expiresAt,
 	}, nil
 }
 
+// resolveDepartment picks the department for a new chat. An explicit
+// department ID wins; otherwise the category and then the topic are looked up
+// in the topic mapping table. Unmapped chats, and chats mapped to a department
+// that is inactive or deleted, go to the global queue.
+func (uc *ChatUsecase) resolveDepartment(ctx context.Context, req *domain.StartChatRequest) (sql.NullString, error) {
+	if req.DepartmentID != nil {
+		department, err := uc.departmentRepo.GetByID(ctx, *req.DepartmentID)
+		if err != nil {
+			return sql.NullString{}, err
+		}
+
+		if department == nil || !department.IsActive {
+			return sql.NullString{}, errors.New("department not found")
+		}
+
+		return sql.NullString{String: department.ID, Valid: true}, nil
+	}
+
+	var keys []string
+	if req.Category != nil && *req.Category != "" {
+		keys = append(keys, *req.Category)
+	}
+	keys = append(keys, req.Topic)
+
+	for _, key := range keys {
+		mapping, err := uc.topicMappingRepo.GetByTopic(ctx, key)
+		if err != nil {
+			return sql.NullString{}, err
+		}
+
+		if mapping == nil {
+			continue
+		}
+
+		departmentID, err := uuid.Parse(mapping.DepartmentID)
+		if err != nil {
+			continue
+		}
+
+		department, err := uc.departmentRepo.GetByID(ctx, departmentID)
+		if err != nil {
+			return sql.NullString{}, err
+		}
+
+		if department != nil && department.IsActive {
+			return sql.NullString{String: department.ID, Valid: true}, nil
+		}
+	}
+
+	return sql.NullString{Valid: false}, nil
+}
+
 // SetSessionContact sets contact information for a chat session
 func (uc *ChatUsecase) SetSessionContact(ctx context.Context, req *domain.SetSessionContactRequest) (*domain.SetSessionContactResponse, error) {
 	// Verify session exists
@@ -633,13 +1381,13 @@ func (uc *ChatUsecase) SetSessionContact(ctx context.Context, req *domain.SetSes
 }
 
 // LinkOSSUser links an anonymous user to an OSS account
-func (uc *ChatUsecase) LinkOSSUser(ctx context.Context, req *domain.LinkOSSUserRequest) (*domain.LinkOSSUserResponse, error) {
-	// Get the anonymous user by browser UUID
+func (uc *ChatUsecase) LinkOSSUser(ctx context.Context, req *domain.LinkOSSUserRequest, chatUserID uuid.UUID) (*domain.LinkOSSUserResponse, error) {
+	// Get the anonymous user by browser UUID, which must be the customer calling
 	chatUser, err := uc.chatUserRepo.GetByBrowserUUID(ctx, req.BrowserUUID)
 	if err != nil {
 		return nil, err
 	}
-	if chatUser == nil {
+	if chatUser == nil || chatUser.ID != chatUserID.String() {
 		return nil, errors.New("anonymous user not found")
 	}
 
@@ -667,18 +1415,8 @@ func (uc *ChatUsecase) LinkOSSUser(ctx context.Context, req *domain.LinkOSSUserR
 
 // GetChatHistory gets chat history for a user
 func (uc *ChatUsecase) GetChatHistory(ctx context.Context, req *domain.GetChatHistoryRequest) (*domain.GetChatHistoryResponse, error) {
-	var chatUser *domain.ChatUser
-	var err error
-
 	// Find chat user
-	if req.BrowserUUID != nil {
-		chatUser, err = uc.chatUserRepo.GetByBrowserUUID(ctx, *req.BrowserUUID)
-	} else if req.OSSUserID != nil {
-		chatUser, err = uc.chatUserRepo.GetByOSSUserID(ctx, *req.OSSUserID)
-	} else {
-		return nil, errors.New("either browser_uuid or oss_user_id must be provided")
-	}
-
+	chatUser, err := uc.chatUserRepo.GetByID(ctx, req.ChatUserID)
 	if err != nil {
 		return nil, err
 	}
@@ -742,7 +1480,7 @@ func (uc *ChatUsecase) GetChatHistory(ctx context.Context, req *domain.GetChatHi
 	}
 
 	// Count total sessions for pagination
-	totalSessions, err := uc.sessionRepo.Count(ctx, "", nil, nil)
+	totalSessions, err := uc.sessionRepo.CountByChatUserID(ctx, chatUserUUID)
 	if err != nil {
 		return nil, err
 	}
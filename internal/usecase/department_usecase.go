@@ -0,0 +1,178 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/domain"
+)
+
+type DepartmentUsecase struct {
+	departmentRepo domain.DepartmentRepository
+	sessionRepo    domain.ChatSessionRepository
+}
+
+func NewDepartmentUsecase(departmentRepo domain.DepartmentRepository, sessionRepo domain.ChatSessionRepository) *DepartmentUsecase {
+	return &DepartmentUsecase{
+		departmentRepo: departmentRepo,
+		sessionRepo:    sessionRepo,
+	}
+}
+
+// GetDepartments returns all departments together with their agent counts by
+// department ID
+func (uc *DepartmentUsecase) GetDepartments(ctx context.Context) ([]*domain.Department, map[string]int, error) {
+	departments, err := uc.departmentRepo.GetAll(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]string, 0, len(departments))
+	for _, department := range departments {
+		ids = append(ids, department.ID)
+	}
+
+	agentCounts, err := uc.departmentRepo.CountUsers(ctx, ids, "agent")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return departments, agentCounts, nil
+}
+
+// GetDepartment returns a department and its agent count
+func (uc *DepartmentUsecase) GetDepartment(ctx context.Context, id uuid.UUID) (*domain.Department, int, error) {
+	department, err := uc.getDepartment(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	agentCounts, err := uc.departmentRepo.CountUsers(ctx, []string{department.ID}, "agent")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return department, agentCounts[department.ID], nil
+}
+
+func (uc *DepartmentUsecase) CreateDepartment(ctx context.Context, req *domain.CreateDepartmentRequest) (*domain.Department, error) {
+	name := strings.TrimSpace(req.Name)
+	if err := uc.checkName(ctx, name, ""); err != nil {
+		return nil, err
+	}
+
+	uuidV7, _ := uuid.NewV7()
+	department := &domain.Department{
+		ID:          uuidV7.String(),
+		Name:        name,
+		Description: optionalString(req.Description),
+		IsActive:    true,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	if err := uc.departmentRepo.Create(ctx, department); err != nil {
+		return nil, err
+	}
+
+	return department, nil
+}
+
+// UpdateDepartment changes name, description and whether the department takes
+// new chats, and returns the department with its agent count. Inactive
+// departments keep their agents and open sessions.
+func (uc *DepartmentUsecase) UpdateDepartment(ctx context.Context, id uuid.UUID, req *domain.UpdateDepartmentRequest) (*domain.Department, int, error) {
+	department, err := uc.getDepartment(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if err := uc.checkName(ctx, name, department.ID); err != nil {
+		return nil, 0, err
+	}
+
+	department.Name = name
+	department.Description = optionalString(req.Description)
+	department.IsActive = req.IsActive
+	department.UpdatedAt = time.Now()
+
+	if err := uc.departmentRepo.Update(ctx, department); err != nil {
+		return nil, 0, err
+	}
+
+	return uc.GetDepartment(ctx, id)
+}
+
+// DeleteDepartment soft-deletes a department. Departments that still have
+// staff or sessions that are not closed must be emptied first.
+func (uc *DepartmentUsecase) DeleteDepartment(ctx context.Context, id uuid.UUID) error {
+	department, err := uc.getDepartment(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	userCounts, err := uc.departmentRepo.CountUsers(ctx, []string{department.ID}, "")
+	if err != nil {
+		return err
+	}
+
+	if userCounts[department.ID] > 0 {
+		return errors.New("department still has agents")
+	}
+
+	for _, status := range []string{"waiting", "active"} {
+		open, err := uc.sessionRepo.Count(ctx, status, nil, &id)
+		if err != nil {
+			return err
+		}
+
+		if open > 0 {
+			return errors.New("department still has open sessions")
+		}
+	}
+
+	return uc.departmentRepo.Delete(ctx, id)
+}
+
+func (uc *DepartmentUsecase) getDepartment(ctx context.Context, id uuid.UUID) (*domain.Department, error) {
+	department, err := uc.departmentRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if department == nil {
+		return nil, errors.New("department not found")
+	}
+
+	return department, nil
+}
+
+// checkName rejects empty names and names already used by another department
+func (uc *DepartmentUsecase) checkName(ctx context.Context, name, exceptID string) error {
+	if name == "" {
+		return errors.New("department name is required")
+	}
+
+	departments, err := uc.departmentRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, department := range departments {
+		if department.ID != exceptID && strings.EqualFold(department.Name, name) {
+			return errors.New("department name already exists")
+		}
+	}
+
+	return nil
+}
+
+func optionalString(value string) sql.NullString {
+	value = strings.TrimSpace(value)
+	return sql.NullString{String: value, Valid: value != ""}
+}
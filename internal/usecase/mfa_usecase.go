@@ -0,0 +1,314 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"github.com/novianakbar/livechat-be/pkg/utils"
+)
+
+const (
+	recoveryCodeCount = 10
+	// recoveryCodeBytes gives codes of 10 hex characters, shown as xxxxx-xxxxx
+	recoveryCodeBytes = 5
+)
+
+type MFAUsecase struct {
+	userRepo     domain.UserRepository
+	mfaRepo      domain.UserMFARepository
+	recoveryRepo domain.UserRecoveryCodeRepository
+	policyRepo   domain.MFAPolicyRepository
+	issuer       string
+}
+
+func NewMFAUsecase(
+	userRepo domain.UserRepository,
+	mfaRepo domain.UserMFARepository,
+	recoveryRepo domain.UserRecoveryCodeRepository,
+	policyRepo domain.MFAPolicyRepository,
+	issuer string,
+) *MFAUsecase {
+	return &MFAUsecase{
+		userRepo:     userRepo,
+		mfaRepo:      mfaRepo,
+		recoveryRepo: recoveryRepo,
+		policyRepo:   policyRepo,
+		issuer:       issuer,
+	}
+}
+
+func (uc *MFAUsecase) GetStatus(ctx context.Context, user *domain.User) (*domain.MFAStatusResponse, error) {
+	enabled, err := uc.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	required, err := uc.policyRepo.IsRequired(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &domain.MFAStatusResponse{
+		Enabled:  enabled,
+		Required: required,
+	}
+
+	if enabled {
+		status.RecoveryCodesRemaining, err = uc.recoveryRepo.CountUnused(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
+
+// Enroll starts TOTP enrollment with a new secret. MFA stays off until a code
+// from the authenticator app is confirmed. Restarting replaces the secret.
+func (uc *MFAUsecase) Enroll(ctx context.Context, user *domain.User) (*domain.MFAEnrollResponse, error) {
+	mfa, err := uc.mfaRepo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if mfa != nil && mfa.Enabled {
+		return nil, errors.New("mfa is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if mfa == nil {
+		uuidV7, _ := uuid.NewV7()
+		mfa = &domain.UserMFA{
+			ID:        uuidV7.String(),
+			UserID:    user.ID,
+			CreatedAt: time.Now(),
+		}
+	}
+	mfa.Secret = secret
+	mfa.LastUsedStep = 0
+	mfa.UpdatedAt = time.Now()
+
+	if err := uc.mfaRepo.Save(ctx, mfa); err != nil {
+		return nil, err
+	}
+
+	return &domain.MFAEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(uc.issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmEnrollment enables MFA once the user proves the authenticator app
+// works, and returns the recovery codes. They are only shown this once.
+func (uc *MFAUsecase) ConfirmEnrollment(ctx context.Context, user *domain.User, code string) ([]string, error) {
+	mfa, err := uc.mfaRepo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if mfa == nil {
+		return nil, errors.New("mfa enrollment has not been started")
+	}
+
+	if mfa.Enabled {
+		return nil, errors.New("mfa is already enabled")
+	}
+
+	step, ok := utils.ValidateTOTP(mfa.Secret, normalizeMFACode(code), time.Now())
+	if !ok {
+		return nil, errors.New("invalid verification code")
+	}
+
+	mfa.Enabled = true
+	mfa.EnabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+	mfa.LastUsedStep = step
+	mfa.UpdatedAt = time.Now()
+
+	if err := uc.mfaRepo.Save(ctx, mfa); err != nil {
+		return nil, err
+	}
+
+	return uc.generateRecoveryCodes(ctx, user.ID)
+}
+
+// Disable turns MFA off after checking the password and a current code.
+// Users whose role requires MFA cannot turn it off.
+func (uc *MFAUsecase) Disable(ctx context.Context, user *domain.User, req *domain.MFADisableRequest) error {
+	required, err := uc.policyRepo.IsRequired(ctx, user.Role)
+	if err != nil {
+		return err
+	}
+
+	if required {
+		return errors.New("mfa is required for your role")
+	}
+
+	matches, err := passwordMatches(ctx, uc.userRepo, user.ID, req.Password)
+	if err != nil {
+		return err
+	}
+
+	if !matches {
+		return errors.New("invalid password")
+	}
+
+	if err := uc.verifyEnabledCode(ctx, user.ID, req.Code); err != nil {
+		return err
+	}
+
+	return uc.removeMFA(ctx, user.ID)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a code
+func (uc *MFAUsecase) RegenerateRecoveryCodes(ctx context.Context, user *domain.User, code string) ([]string, error) {
+	if err := uc.verifyEnabledCode(ctx, user.ID, code); err != nil {
+		return nil, err
+	}
+
+	return uc.generateRecoveryCodes(ctx, user.ID)
+}
+
+// ResetForUser removes MFA from a user who lost their device, so they can
+// enroll again after logging in with the password
+func (uc *MFAUsecase) ResetForUser(ctx context.Context, userID string) error {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	return uc.removeMFA(ctx, user.ID)
+}
+
+func (uc *MFAUsecase) GetPolicies(ctx context.Context) ([]*domain.MFAPolicy, error) {
+	return uc.policyRepo.GetAll(ctx)
+}
+
+// SetPolicy requires or stops requiring MFA for a role. Users of the role who
+// have not enrolled are limited to the auth routes until they do.
+func (uc *MFAUsecase) SetPolicy(ctx context.Context, req *domain.SetMFAPolicyRequest) error {
+	if !isStaffRole(req.Role) {
+		return errors.New("invalid role")
+	}
+
+	return uc.policyRepo.SetRequired(ctx, req.Role, req.Required)
+}
+
+// IsEnabled reports whether the user has confirmed MFA enrollment
+func (uc *MFAUsecase) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	mfa, err := uc.mfaRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return mfa != nil && mfa.Enabled, nil
+}
+
+// EnrollmentRequired reports whether the user's role requires MFA that the
+// user has not set up yet
+func (uc *MFAUsecase) EnrollmentRequired(ctx context.Context, user *domain.User) (bool, error) {
+	required, err := uc.policyRepo.IsRequired(ctx, user.Role)
+	if err != nil || !required {
+		return false, err
+	}
+
+	enabled, err := uc.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+	return !enabled, nil
+}
+
+// VerifyCode checks a TOTP code, or else a recovery code, of a user with MFA
+// enabled. Accepted codes cannot be used again.
+func (uc *MFAUsecase) VerifyCode(ctx context.Context, userID, code string) (bool, error) {
+	mfa, err := uc.mfaRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if mfa == nil || !mfa.Enabled {
+		return false, nil
+	}
+
+	code = normalizeMFACode(code)
+	if step, ok := utils.ValidateTOTP(mfa.Secret, code, time.Now()); ok {
+		return uc.mfaRepo.UpdateLastUsedStep(ctx, userID, step)
+	}
+
+	return uc.recoveryRepo.Use(ctx, userID, utils.HashToken(code))
+}
+
+func (uc *MFAUsecase) verifyEnabledCode(ctx context.Context, userID, code string) error {
+	enabled, err := uc.IsEnabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !enabled {
+		return errors.New("mfa is not enabled")
+	}
+
+	valid, err := uc.VerifyCode(ctx, userID, code)
+	if err != nil {
+		return err
+	}
+
+	if !valid {
+		return errors.New("invalid verification code")
+	}
+
+	return nil
+}
+
+func (uc *MFAUsecase) generateRecoveryCodes(ctx context.Context, userID string) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]*domain.UserRecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := utils.GenerateSecureToken(recoveryCodeBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		uuidV7, _ := uuid.NewV7()
+		records = append(records, &domain.UserRecoveryCode{
+			ID:        uuidV7.String(),
+			UserID:    userID,
+			CodeHash:  utils.HashToken(code),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		})
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+
+	if err := uc.recoveryRepo.ReplaceForUser(ctx, userID, records); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (uc *MFAUsecase) removeMFA(ctx context.Context, userID string) error {
+	if err := uc.mfaRepo.DeleteByUserID(ctx, userID); err != nil {
+		return err
+	}
+	return uc.recoveryRepo.DeleteByUserID(ctx, userID)
+}
+
+// normalizeMFACode accepts codes typed with spaces or dashes and in any case
+func normalizeMFACode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, " ", "")
+	return strings.ReplaceAll(code, "-", "")
+}
@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"github.com/novianakbar/livechat-be/pkg/utils"
+)
+
+// fakeMFARepository keeps one user's MFA in memory. UpdateLastUsedStep has
+// the same condition as the SQL update: only a later step is recorded.
+type fakeMFARepository struct {
+	mfa *domain.UserMFA
+}
+
+func (r *fakeMFARepository) GetByUserID(ctx context.Context, userID string) (*domain.UserMFA, error) {
+	if r.mfa == nil || r.mfa.UserID != userID {
+		return nil, nil
+	}
+	return r.mfa, nil
+}
+
+func (r *fakeMFARepository) Save(ctx context.Context, mfa *domain.UserMFA) error {
+	r.mfa = mfa
+	return nil
+}
+
+func (r *fakeMFARepository) UpdateLastUsedStep(ctx context.Context, userID string, step int64) (bool, error) {
+	if r.mfa == nil || r.mfa.UserID != userID || r.mfa.LastUsedStep >= step {
+		return false, nil
+	}
+	r.mfa.LastUsedStep = step
+	return true, nil
+}
+
+func (r *fakeMFARepository) DeleteByUserID(ctx context.Context, userID string) error {
+	r.mfa = nil
+	return nil
+}
+
+// fakeRecoveryCodeRepository has no recovery codes, so only TOTP codes verify
+type fakeRecoveryCodeRepository struct{}
+
+func (fakeRecoveryCodeRepository) ReplaceForUser(ctx context.Context, userID string, codes []*domain.UserRecoveryCode) error {
+	return nil
+}
+
+func (fakeRecoveryCodeRepository) Use(ctx context.Context, userID, codeHash string) (bool, error) {
+	return false, nil
+}
+
+func (fakeRecoveryCodeRepository) CountUnused(ctx context.Context, userID string) (int64, error) {
+	return 0, nil
+}
+
+func (fakeRecoveryCodeRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	return nil
+}
+
+func newTestMFAUsecase(t *testing.T) (*MFAUsecase, *fakeMFARepository) {
+	t.Helper()
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret: %v", err)
+	}
+
+	mfaRepo := &fakeMFARepository{mfa: &domain.UserMFA{
+		ID:      "mfa-1",
+		UserID:  "user-1",
+		Secret:  secret,
+		Enabled: true,
+	}}
+
+	return NewMFAUsecase(nil, mfaRepo, fakeRecoveryCodeRepository{}, nil, "LiveChat"), mfaRepo
+}
+
+func TestVerifyCodeRefusesReplayedStep(t *testing.T) {
+	uc, mfaRepo := newTestMFAUsecase(t)
+	ctx := context.Background()
+
+	step := time.Now().Unix() / 30
+	code, err := utils.TOTPCode(mfaRepo.mfa.Secret, step)
+	if err != nil {
+		t.Fatalf("TOTPCode: %v", err)
+	}
+
+	valid, err := uc.VerifyCode(ctx, "user-1", code)
+	if err != nil {
+		t.Fatalf("VerifyCode: %v", err)
+	}
+	if !valid {
+		t.Fatal("first use of the code was refused")
+	}
+	if mfaRepo.mfa.LastUsedStep != step {
+		t.Errorf("LastUsedStep = %d, want %d", mfaRepo.mfa.LastUsedStep, step)
+	}
+
+	valid, err = uc.VerifyCode(ctx, "user-1", code)
+	if err != nil {
+		t.Fatalf("VerifyCode: %v", err)
+	}
+	if valid {
+		t.Error("replayed code was accepted")
+	}
+}
+
+func TestVerifyCodeRefusesEarlierStep(t *testing.T) {
+	uc, mfaRepo := newTestMFAUsecase(t)
+	ctx := context.Background()
+
+	// The code of the current period was used; the still valid code of the
+	// previous period must not be accepted after it
+	step := time.Now().Unix() / 30
+	mfaRepo.mfa.LastUsedStep = step
+
+	code, err := utils.TOTPCode(mfaRepo.mfa.Secret, step-1)
+	if err != nil {
+		t.Fatalf("TOTPCode: %v", err)
+	}
+
+	valid, err := uc.VerifyCode(ctx, "user-1", code)
+	if err != nil {
+		t.Fatalf("VerifyCode: %v", err)
+	}
+	if valid {
+		t.Error("code of an earlier step was accepted")
+	}
+}
@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"github.com/novianakbar/livechat-be/pkg/utils"
+)
+
+const (
+	// resetTokenBytes is the entropy of a reset token, hex encoded in the link
+	resetTokenBytes = 32
+	// resetEmailTimeout bounds sending the reset email in the background
+	resetEmailTimeout = 30 * time.Second
+)
+
+// TokenRevoker signs a user out everywhere
+type TokenRevoker interface {
+	RevokeAllTokens(ctx context.Context, userID string) error
+}
+
+type PasswordResetUsecase struct {
+	userRepo       domain.UserRepository
+	resetTokenRepo domain.PasswordResetTokenRepository
+	emailService   domain.EmailService
+	tokenRevoker   TokenRevoker
+	tokenTTL       time.Duration
+}
+
+func NewPasswordResetUsecase(
+	userRepo domain.UserRepository,
+	resetTokenRepo domain.PasswordResetTokenRepository,
+	emailService domain.EmailService,
+	tokenRevoker TokenRevoker,
+	tokenTTL time.Duration,
+) *PasswordResetUsecase {
+	return &PasswordResetUsecase{
+		userRepo:       userRepo,
+		resetTokenRepo: resetTokenRepo,
+		emailService:   emailService,
+		tokenRevoker:   tokenRevoker,
+		tokenTTL:       tokenTTL,
+	}
+}
+
+// ForgotPassword emails a reset link to the user with this email. The outcome
+// is the same whether or not the account exists, and the link is created and
+// sent in the background so response times do not reveal it either.
+func (uc *PasswordResetUsecase) ForgotPassword(ctx context.Context, email string) error {
+	user, err := uc.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	if user == nil || !user.IsActive {
+		return nil
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), resetEmailTimeout)
+		defer cancel()
+
+		if err := uc.sendResetLink(ctx, user); err != nil {
+			log.Printf("Failed to send password reset email to user %s: %v", user.ID, err)
+		}
+	}()
+
+	return nil
+}
+
+// sendResetLink replaces any outstanding reset token of the user with a new
+// one and emails it
+func (uc *PasswordResetUsecase) sendResetLink(ctx context.Context, user *domain.User) error {
+	token, err := utils.GenerateSecureToken(resetTokenBytes)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.resetTokenRepo.InvalidateForUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	uuidV7, _ := uuid.NewV7()
+	resetToken := &domain.PasswordResetToken{
+		ID:        uuidV7.String(),
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(uc.tokenTTL),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := uc.resetTokenRepo.Create(ctx, resetToken); err != nil {
+		return err
+	}
+
+	_, err = uc.emailService.SendPasswordResetEmail(ctx, user.Email, token)
+	return err
+}
+
+// ResetPassword sets a new password with a reset token. The token is consumed
+// and every existing login of the user is revoked.
+func (uc *PasswordResetUsecase) ResetPassword(ctx context.Context, req *domain.ResetPasswordRequest) error {
+	if err := checkPasswordLength(req.NewPassword); err != nil {
+		return err
+	}
+
+	resetToken, err := uc.resetTokenRepo.GetByTokenHash(ctx, utils.HashToken(req.Token))
+	if err != nil {
+		return err
+	}
+
+	if resetToken == nil || resetToken.UsedAt.Valid || time.Now().After(resetToken.ExpiresAt) {
+		return errors.New("invalid or expired reset token")
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, resetToken.UserID)
+	if err != nil {
+		return err
+	}
+
+	if user == nil || !user.IsActive {
+		return errors.New("invalid or expired reset token")
+	}
+
+	used, err := uc.resetTokenRepo.MarkUsed(ctx, resetToken.ID)
+	if err != nil {
+		return err
+	}
+
+	if !used {
+		return errors.New("invalid or expired reset token")
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.userRepo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		return err
+	}
+
+	// Other links requested before this one must not work anymore either
+	if err := uc.resetTokenRepo.InvalidateForUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return uc.tokenRevoker.RevokeAllTokens(ctx, user.ID)
+}
@@ -0,0 +1,158 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/novianakbar/livechat-be/internal/domain"
+)
+
+// permissionCacheTTL bounds how long permission changes made on another
+// instance take to apply here
+const permissionCacheTTL = time.Minute
+
+// staffRoles are the roles users can have, in order of privilege
+var staffRoles = []string{"admin", "supervisor", "agent"}
+
+// PermissionUsecase answers which role may do what. Role permissions are read
+// on every authorized request, so they are cached in memory.
+type PermissionUsecase struct {
+	permissionRepo domain.PermissionRepository
+
+	mu       sync.RWMutex
+	cache    map[string]map[string]bool // role -> permission -> granted
+	loadedAt time.Time
+}
+
+func NewPermissionUsecase(permissionRepo domain.PermissionRepository) *PermissionUsecase {
+	return &PermissionUsecase{
+		permissionRepo: permissionRepo,
+	}
+}
+
+// HasPermission reports whether users of the role have the permission
+func (uc *PermissionUsecase) HasPermission(ctx context.Context, role, permission string) (bool, error) {
+	cache, err := uc.rolePermissions(ctx)
+	if err != nil {
+		return false, err
+	}
+	return cache[role][permission], nil
+}
+
+// GetPermissions lists every permission and what each role is granted
+func (uc *PermissionUsecase) GetPermissions(ctx context.Context) (*domain.PermissionsResponse, error) {
+	permissions, err := uc.permissionRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rolePermissions, err := uc.permissionRepo.GetRolePermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	granted := make(map[string][]string)
+	for _, rp := range rolePermissions {
+		granted[rp.Role] = append(granted[rp.Role], rp.Permission)
+	}
+
+	roles := make([]*domain.RolePermissionsResponse, 0, len(staffRoles))
+	for _, role := range staffRoles {
+		rolePerms := granted[role]
+		if rolePerms == nil {
+			rolePerms = []string{}
+		}
+		roles = append(roles, &domain.RolePermissionsResponse{
+			Role:        role,
+			Permissions: rolePerms,
+		})
+	}
+
+	return &domain.PermissionsResponse{
+		Permissions: permissions,
+		Roles:       roles,
+	}, nil
+}
+
+// SetRolePermissions replaces the permissions of a role. Admins keep their
+// permissions so nobody can lock themselves out of this endpoint.
+func (uc *PermissionUsecase) SetRolePermissions(ctx context.Context, role string, req *domain.SetRolePermissionsRequest) error {
+	if !isStaffRole(role) {
+		return errors.New("invalid role")
+	}
+
+	if role == "admin" {
+		return errors.New("admin permissions cannot be changed")
+	}
+
+	permissions, err := uc.permissionRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		known[permission.Name] = true
+	}
+
+	seen := make(map[string]bool, len(req.Permissions))
+	unique := make([]string, 0, len(req.Permissions))
+	for _, permission := range req.Permissions {
+		if !known[permission] {
+			return errors.New("unknown permission: " + permission)
+		}
+		if !seen[permission] {
+			seen[permission] = true
+			unique = append(unique, permission)
+		}
+	}
+
+	if err := uc.permissionRepo.SetRolePermissions(ctx, role, unique); err != nil {
+		return err
+	}
+
+	uc.mu.Lock()
+	uc.cache = nil
+	uc.mu.Unlock()
+	return nil
+}
+
+func (uc *PermissionUsecase) rolePermissions(ctx context.Context) (map[string]map[string]bool, error) {
+	uc.mu.RLock()
+	cache, loadedAt := uc.cache, uc.loadedAt
+	uc.mu.RUnlock()
+
+	if cache != nil && time.Since(loadedAt) < permissionCacheTTL {
+		return cache, nil
+	}
+
+	rolePermissions, err := uc.permissionRepo.GetRolePermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cache = make(map[string]map[string]bool)
+	for _, rp := range rolePermissions {
+		if cache[rp.Role] == nil {
+			cache[rp.Role] = make(map[string]bool)
+		}
+		cache[rp.Role][rp.Permission] = true
+	}
+
+	uc.mu.Lock()
+	uc.cache = cache
+	uc.loadedAt = time.Now()
+	uc.mu.Unlock()
+	return cache, nil
+}
+
+func isStaffRole(role string) bool {
+	for _, staffRole := range staffRoles {
+		if role == staffRole {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,317 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/domain"
+	"github.com/novianakbar/livechat-be/pkg/utils"
+)
+
+// oidcRandomBytes is the entropy of the state, nonce and PKCE verifier. Hex
+// encoded, 32 bytes give a 64 character verifier, within the 43-128 of PKCE.
+const oidcRandomBytes = 32
+
+// OIDCProvider runs the authorization code flow against the identity provider
+type OIDCProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error)
+	// Exchange redeems the code and returns the claims of the verified ID token
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (map[string]interface{}, error)
+}
+
+// OIDCStateStore keeps SSO logins in progress between redirect and callback
+type OIDCStateStore interface {
+	Save(ctx context.Context, state string, authState *domain.OIDCAuthState, ttl time.Duration) error
+	Consume(ctx context.Context, state string) (*domain.OIDCAuthState, error)
+}
+
+// VerifiedLogin hands out tokens to a user whose identity was checked elsewhere
+type VerifiedLogin interface {
+	LoginVerifiedUser(ctx context.Context, user *domain.User, clientIP, userAgent string) (*domain.LoginResponse, error)
+}
+
+// SSOClaimMapping maps ID token claims onto users
+type SSOClaimMapping struct {
+	EmailClaim        string
+	GroupsClaim       string
+	RoleMapping       map[string]string // IdP group -> role
+	DepartmentMapping map[string]string // IdP group -> department ID
+	// JITProvisioning creates users on their first SSO login instead of
+	// rejecting emails without an account
+	JITProvisioning bool
+	// AllowUnverifiedEmail skips the email_verified check, for IdPs that
+	// never send the claim
+	AllowUnverifiedEmail bool
+}
+
+type SSOUsecase struct {
+	provider       OIDCProvider
+	stateStore     OIDCStateStore
+	login          VerifiedLogin
+	userRepo       domain.UserRepository
+	departmentRepo domain.DepartmentRepository
+	mapping        SSOClaimMapping
+	stateTTL       time.Duration
+}
+
+// NewSSOUsecase creates the SSO usecase. A nil provider means SSO is not
+// configured and every call fails with "sso is not configured".
+func NewSSOUsecase(
+	provider OIDCProvider,
+	stateStore OIDCStateStore,
+	login VerifiedLogin,
+	userRepo domain.UserRepository,
+	departmentRepo domain.DepartmentRepository,
+	mapping SSOClaimMapping,
+	stateTTL time.Duration,
+) *SSOUsecase {
+	return &SSOUsecase{
+		provider:       provider,
+		stateStore:     stateStore,
+		login:          login,
+		userRepo:       userRepo,
+		departmentRepo: departmentRepo,
+		mapping:        mapping,
+		stateTTL:       stateTTL,
+	}
+}
+
+// Authorize starts an SSO login and returns the IdP URL to send the browser to
+func (uc *SSOUsecase) Authorize(ctx context.Context) (*domain.SSOAuthorizeResponse, error) {
+	if uc.provider == nil {
+		return nil, errors.New("sso is not configured")
+	}
+
+	state, err := utils.GenerateSecureToken(oidcRandomBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	authState := &domain.OIDCAuthState{}
+	if authState.CodeVerifier, err = utils.GenerateSecureToken(oidcRandomBytes); err != nil {
+		return nil, err
+	}
+	if authState.Nonce, err = utils.GenerateSecureToken(oidcRandomBytes); err != nil {
+		return nil, err
+	}
+
+	authURL, err := uc.provider.AuthCodeURL(ctx, state, authState.Nonce, authState.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.stateStore.Save(ctx, state, authState, uc.stateTTL); err != nil {
+		return nil, err
+	}
+
+	return &domain.SSOAuthorizeResponse{
+		AuthorizationURL: authURL,
+		State:            state,
+	}, nil
+}
+
+// Callback finishes an SSO login with the code and state the IdP redirected
+// back with. The email claim selects the user; mapped groups set the role and
+// department on every login.
+func (uc *SSOUsecase) Callback(ctx context.Context, req *domain.SSOCallbackRequest, clientIP, userAgent string) (*domain.LoginResponse, error) {
+	if uc.provider == nil {
+		return nil, errors.New("sso is not configured")
+	}
+
+	authState, err := uc.stateStore.Consume(ctx, req.State)
+	if err != nil {
+		return nil, err
+	}
+
+	if authState == nil {
+		return nil, errors.New("invalid or expired sso state")
+	}
+
+	claims, err := uc.provider.Exchange(ctx, req.Code, authState.CodeVerifier, authState.Nonce)
+	if err != nil {
+		log.Printf("SSO code exchange failed: %v", err)
+		return nil, errors.New("sso login failed")
+	}
+
+	email, _ := claims[uc.mapping.EmailClaim].(string)
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, errors.New("sso account has no email")
+	}
+
+	// The email is what links the IdP account to a user, so an address the
+	// IdP has not verified could take over someone else's account
+	if verified, _ := claims["email_verified"].(bool); !verified && !uc.mapping.AllowUnverifiedEmail {
+		return nil, errors.New("sso email is not verified")
+	}
+
+	groups := claimStrings(claims[uc.mapping.GroupsClaim])
+	role := uc.mapRole(groups)
+	departmentID := uc.mapDepartment(ctx, groups)
+
+	user, err := uc.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		user, err = uc.provisionUser(ctx, email, claims, role, departmentID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if !user.IsActive {
+			return nil, errors.New("user account is inactive")
+		}
+
+		if err := uc.syncUser(ctx, user, role, departmentID); err != nil {
+			return nil, err
+		}
+	}
+
+	return uc.login.LoginVerifiedUser(ctx, user, clientIP, userAgent)
+}
+
+// provisionUser creates the account of a first-time SSO user. The password is
+// random and never shown, so the account can only sign in through SSO until
+// a password reset.
+func (uc *SSOUsecase) provisionUser(ctx context.Context, email string, claims map[string]interface{}, role string, departmentID sql.NullString) (*domain.User, error) {
+	if !uc.mapping.JITProvisioning {
+		return nil, errors.New("no account exists for this sso user")
+	}
+
+	if role == "" {
+		return nil, errors.New("sso user has no role")
+	}
+
+	if role == "supervisor" && !departmentID.Valid {
+		return nil, errors.New("sso supervisor has no department")
+	}
+
+	password, err := utils.GenerateSecureToken(oidcRandomBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	name, _ := claims["name"].(string)
+	if name == "" {
+		name = email
+	}
+
+	uuidV7, _ := uuid.NewV7()
+	user := &domain.User{
+		ID:           uuidV7.String(),
+		Email:        email,
+		Password:     hashedPassword,
+		Name:         name,
+		Role:         role,
+		IsActive:     true,
+		DepartmentID: departmentID,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	if err := uc.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Provisioned SSO user %s (%s) with role %s", user.ID, email, role)
+	return user, nil
+}
+
+// syncUser applies the role and department mapped from the IdP groups. Users
+// whose groups map to nothing keep what they have.
+func (uc *SSOUsecase) syncUser(ctx context.Context, user *domain.User, role string, departmentID sql.NullString) error {
+	// A mapped supervisor role needs a department, mapped now or kept from before
+	if role == "supervisor" && !departmentID.Valid && !user.DepartmentID.Valid {
+		return errors.New("sso supervisor has no department")
+	}
+
+	changed := false
+	if role != "" && role != user.Role {
+		user.Role = role
+		changed = true
+	}
+
+	if departmentID.Valid && departmentID != user.DepartmentID {
+		setUserDepartment(user, departmentID)
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	user.UpdatedAt = time.Now()
+	return uc.userRepo.Update(ctx, user)
+}
+
+func (uc *SSOUsecase) mapRole(groups []string) string {
+	mapped := make(map[string]bool)
+	for _, group := range groups {
+		if role, ok := uc.mapping.RoleMapping[group]; ok {
+			mapped[role] = true
+		}
+	}
+
+	// Users whose groups map to several roles get the most privileged one
+	for _, role := range staffRoles {
+		if mapped[role] {
+			return role
+		}
+	}
+	return ""
+}
+
+// mapDepartment returns the department of the first mapped group that still
+// exists. Mappings to unknown departments are logged and skipped.
+func (uc *SSOUsecase) mapDepartment(ctx context.Context, groups []string) sql.NullString {
+	for _, group := range groups {
+		value, ok := uc.mapping.DepartmentMapping[group]
+		if !ok {
+			continue
+		}
+
+		departmentID, err := uuid.Parse(value)
+		if err != nil {
+			log.Printf("SSO department mapping of group %s is not a valid ID: %s", group, value)
+			continue
+		}
+
+		department, err := uc.departmentRepo.GetByID(ctx, departmentID)
+		if err != nil || department == nil {
+			log.Printf("SSO department mapping of group %s points to unknown department %s", group, value)
+			continue
+		}
+
+		return sql.NullString{String: department.ID, Valid: true}
+	}
+	return sql.NullString{}
+}
+
+// claimStrings reads a claim holding either one string or a list of strings
+func claimStrings(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
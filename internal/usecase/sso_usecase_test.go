@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/novianakbar/livechat-be/internal/domain"
+)
+
+// fakeUserRepository records updated users. Other methods are not used by
+// syncUser and panic through the nil embedded interface.
+type fakeUserRepository struct {
+	domain.UserRepository
+	updated []*domain.User
+}
+
+func (r *fakeUserRepository) Update(ctx context.Context, user *domain.User) error {
+	r.updated = append(r.updated, user)
+	return nil
+}
+
+func TestSyncUserSupervisorNeedsDepartment(t *testing.T) {
+	department := sql.NullString{String: "department-1", Valid: true}
+
+	tests := []struct {
+		name           string
+		role           string
+		userDepartment sql.NullString
+		departmentID   sql.NullString
+		wantErr        bool
+		wantUpdate     bool
+	}{
+		{name: "agent becomes supervisor without department", role: "supervisor", wantErr: true},
+		{name: "agent becomes supervisor with mapped department", role: "supervisor", departmentID: department, wantUpdate: true},
+		{name: "agent becomes supervisor keeping department", role: "supervisor", userDepartment: department, wantUpdate: true},
+		{name: "agent stays agent without department", role: "agent"},
+		{name: "unmapped role", role: ""},
+	}
+
+	for _, tt := range tests {
+		userRepo := &fakeUserRepository{}
+		uc := &SSOUsecase{userRepo: userRepo}
+		user := &domain.User{ID: "user-1", Role: "agent", DepartmentID: tt.userDepartment}
+
+		err := uc.syncUser(context.Background(), user, tt.role, tt.departmentID)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			if user.Role != "agent" || len(userRepo.updated) > 0 {
+				t.Errorf("%s: user was changed to role %s", tt.name, user.Role)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+
+		if updated := len(userRepo.updated) > 0; updated != tt.wantUpdate {
+			t.Errorf("%s: updated = %v, want %v", tt.name, updated, tt.wantUpdate)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/novianakbar/livechat-be/internal/domain"
+)
+
+// defaultTagColor matches the column default of chat_tags.color
+const defaultTagColor = "#007bff"
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type TagUsecase struct {
+	tagRepo        domain.ChatTagRepository
+	sessionTagRepo domain.ChatSessionTagRepository
+	sessionRepo    domain.ChatSessionRepository
+}
+
+func NewTagUsecase(tagRepo domain.ChatTagRepository, sessionTagRepo domain.ChatSessionTagRepository, sessionRepo domain.ChatSessionRepository) *TagUsecase {
+	return &TagUsecase{
+		tagRepo:        tagRepo,
+		sessionTagRepo: sessionTagRepo,
+		sessionRepo:    sessionRepo,
+	}
+}
+
+func (uc *TagUsecase) GetTags(ctx context.Context) ([]*domain.ChatTag, error) {
+	return uc.tagRepo.GetAll(ctx)
+}
+
+func (uc *TagUsecase) CreateTag(ctx context.Context, req *domain.CreateTagRequest) (*domain.ChatTag, error) {
+	name := strings.TrimSpace(req.Name)
+	if err := uc.checkName(ctx, name, ""); err != nil {
+		return nil, err
+	}
+
+	color, err := tagColor(req.Color)
+	if err != nil {
+		return nil, err
+	}
+
+	uuidV7, _ := uuid.NewV7()
+	tag := &domain.ChatTag{
+		ID:        uuidV7.String(),
+		Name:      name,
+		Color:     color,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := uc.tagRepo.Create(ctx, tag); err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+func (uc *TagUsecase) UpdateTag(ctx context.Context, id uuid.UUID, req *domain.UpdateTagRequest) (*domain.ChatTag, error) {
+	tag, err := uc.getTag(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if err := uc.checkName(ctx, name, tag.ID); err != nil {
+		return nil, err
+	}
+
+	color, err := tagColor(req.Color)
+	if err != nil {
+		return nil, err
+	}
+
+	tag.Name = name
+	tag.Color = color
+	tag.UpdatedAt = time.Now()
+
+	if err := uc.tagRepo.Update(ctx, tag); err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+// DeleteTag soft-deletes a tag and removes it from every session carrying it
+func (uc *TagUsecase) DeleteTag(ctx context.Context, id uuid.UUID) error {
+	if _, err := uc.getTag(ctx, id); err != nil {
+		return err
+	}
+
+	if err := uc.sessionTagRepo.DeleteByTagID(ctx, id); err != nil {
+		return err
+	}
+
+	return uc.tagRepo.Delete(ctx, id)
+}
+
+// AddTagToSession tags a session and returns all of its tags. Adding a tag
+// the session already has changes nothing.
+func (uc *TagUsecase) AddTagToSession(ctx context.Context, sessionID, tagID uuid.UUID) ([]*domain.ChatTag, error) {
+	if err := uc.checkSession(ctx, sessionID); err != nil {
+		return nil, err
+	}
+
+	if _, err := uc.getTag(ctx, tagID); err != nil {
+		return nil, err
+	}
+
+	tags, err := uc.sessionTags(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range tags {
+		if tag.ID == tagID.String() {
+			return tags, nil
+		}
+	}
+
+	uuidV7, _ := uuid.NewV7()
+	if err := uc.sessionTagRepo.Create(ctx, &domain.ChatSessionTag{
+		ID:        uuidV7.String(),
+		SessionID: sessionID.String(),
+		TagID:     tagID.String(),
+		CreatedAt: time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+
+	return uc.sessionTags(ctx, sessionID)
+}
+
+// RemoveTagFromSession removes a tag from a session and returns the tags left
+func (uc *TagUsecase) RemoveTagFromSession(ctx context.Context, sessionID, tagID uuid.UUID) ([]*domain.ChatTag, error) {
+	if err := uc.checkSession(ctx, sessionID); err != nil {
+		return nil, err
+	}
+
+	tags, err := uc.sessionTags(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	tagged := false
+	for _, tag := range tags {
+		if tag.ID == tagID.String() {
+			tagged = true
+			break
+		}
+	}
+
+	if !tagged {
+		return nil, errors.New("session does not have this tag")
+	}
+
+	if err := uc.sessionTagRepo.Delete(ctx, sessionID, tagID); err != nil {
+		return nil, err
+	}
+
+	return uc.sessionTags(ctx, sessionID)
+}
+
+// GetSessionTags returns the tags of the given sessions by session ID
+func (uc *TagUsecase) GetSessionTags(ctx context.Context, sessionIDs []string) (map[string][]*domain.ChatTag, error) {
+	sessionTags, err := uc.sessionTagRepo.GetBySessionIDs(ctx, sessionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string][]*domain.ChatTag, len(sessionIDs))
+	for _, sessionTag := range sessionTags {
+		// Tag is nil when the tag was deleted after being added
+		if sessionTag.Tag != nil {
+			tags[sessionTag.SessionID] = append(tags[sessionTag.SessionID], sessionTag.Tag)
+		}
+	}
+
+	return tags, nil
+}
+
+func (uc *TagUsecase) sessionTags(ctx context.Context, sessionID uuid.UUID) ([]*domain.ChatTag, error) {
+	tags, err := uc.GetSessionTags(ctx, []string{sessionID.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	return tags[sessionID.String()], nil
+}
+
+func (uc *TagUsecase) checkSession(ctx context.Context, sessionID uuid.UUID) error {
+	session, err := uc.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if session == nil {
+		return errors.New("chat session not found")
+	}
+
+	return nil
+}
+
+func (uc *TagUsecase) getTag(ctx context.Context, id uuid.UUID) (*domain.ChatTag, error) {
+	tag, err := uc.tagRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if tag == nil {
+		return nil, errors.New("tag not found")
+	}
+
+	return tag, nil
+}
+
+// checkName rejects empty names and names already used by another tag
+func (uc *TagUsecase) checkName(ctx context.Context, name, exceptID string) error {
+	if name == "" {
+		return errors.New("tag name is required")
+	}
+
+	tags, err := uc.tagRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if tag.ID != exceptID && strings.EqualFold(tag.Name, name) {
+			return errors.New("tag name already exists")
+		}
+	}
+
+	return nil
+}
+
+// tagColor validates a #rrggbb color, using the default when none is given
+func tagColor(color string) (string, error) {
+	color = strings.TrimSpace(color)
+	if color == "" {
+		return defaultTagColor, nil
+	}
+
+	if !tagColorPattern.MatchString(color) {
+		return "", errors.New("tag color must be a hex color like #007bff")
+	}
+
+	return strings.ToLower(color), nil
+}
@@ -2,18 +2,38 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/novianakbar/livechat-be/internal/domain"
 )
 
+// AgentSessionReleaser hands the open sessions of an agent to other agents
+type AgentSessionReleaser interface {
+	ReleaseAgentSessions(ctx context.Context, agentID uuid.UUID, reason string) error
+}
+
 type UserUsecase struct {
-	userRepo domain.UserRepository
+	userRepo        domain.UserRepository
+	departmentRepo  domain.DepartmentRepository
+	tokenRevoker    TokenRevoker
+	sessionReleaser AgentSessionReleaser
 }
 
-func NewUserUsecase(userRepo domain.UserRepository) *UserUsecase {
+func NewUserUsecase(
+	userRepo domain.UserRepository,
+	departmentRepo domain.DepartmentRepository,
+	tokenRevoker TokenRevoker,
+	sessionReleaser AgentSessionReleaser,
+) *UserUsecase {
 	return &UserUsecase{
-		userRepo: userRepo,
+		userRepo:        userRepo,
+		departmentRepo:  departmentRepo,
+		tokenRevoker:    tokenRevoker,
+		sessionReleaser: sessionReleaser,
 	}
 }
 
@@ -56,3 +76,147 @@ func (uc *UserUsecase) GetAgents(ctx context.Context) ([]*domain.User, error) {
 
 	return agents, nil
 }
+
+// UpdateUser changes the email, name, role and department of a staff user.
+// Admins cannot change their own role.
+func (uc *UserUsecase) UpdateUser(ctx context.Context, actorID string, userID uuid.UUID, req *domain.UpdateUserRequest) (*domain.User, error) {
+	user, err := uc.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isStaffRole(req.Role) {
+		return nil, errors.New("invalid role")
+	}
+
+	if user.ID == actorID && req.Role != user.Role {
+		return nil, errors.New("you cannot change your own role")
+	}
+
+	email := strings.TrimSpace(req.Email)
+	name := strings.TrimSpace(req.Name)
+	if email == "" || name == "" {
+		return nil, errors.New("email and name are required")
+	}
+
+	if !strings.EqualFold(email, user.Email) {
+		existingUser, err := uc.userRepo.GetByEmail(ctx, email)
+		if err != nil {
+			return nil, err
+		}
+
+		if existingUser != nil {
+			return nil, errors.New("user with this email already exists")
+		}
+	}
+
+	var departmentID sql.NullString
+	if req.DepartmentID != nil {
+		department, err := uc.departmentRepo.GetByID(ctx, *req.DepartmentID)
+		if err != nil {
+			return nil, err
+		}
+
+		if department == nil {
+			return nil, errors.New("department not found")
+		}
+
+		departmentID = sql.NullString{String: department.ID, Valid: true}
+	}
+
+	// Supervisors only see their own department, so they need one
+	if req.Role == "supervisor" && !departmentID.Valid {
+		return nil, errors.New("supervisor must belong to a department")
+	}
+
+	user.Email = email
+	user.Name = name
+	user.Role = req.Role
+	setUserDepartment(user, departmentID)
+	user.UpdatedAt = time.Now()
+
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return uc.GetUser(ctx, userID)
+}
+
+// SetActive activates or deactivates a staff user. Deactivated users are
+// signed out everywhere and their open sessions go to other agents.
+func (uc *UserUsecase) SetActive(ctx context.Context, actorID string, userID uuid.UUID, active bool) error {
+	user, err := uc.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.ID == actorID && !active {
+		return errors.New("you cannot deactivate your own account")
+	}
+
+	if user.IsActive == active {
+		return nil
+	}
+
+	user.IsActive = active
+	user.Department = nil
+	user.UpdatedAt = time.Now()
+
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	if active {
+		return nil
+	}
+
+	if err := uc.tokenRevoker.RevokeAllTokens(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return uc.sessionReleaser.ReleaseAgentSessions(ctx, userID, user.Name+" was deactivated")
+}
+
+// DeleteUser soft-deletes a staff user after signing them out everywhere and
+// hands their open sessions to other agents
+func (uc *UserUsecase) DeleteUser(ctx context.Context, actorID string, userID uuid.UUID) error {
+	user, err := uc.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.ID == actorID {
+		return errors.New("you cannot delete your own account")
+	}
+
+	// Revoking looks the user up, so it has to happen before the delete
+	if err := uc.tokenRevoker.RevokeAllTokens(ctx, user.ID); err != nil {
+		return err
+	}
+
+	if err := uc.userRepo.Delete(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return uc.sessionReleaser.ReleaseAgentSessions(ctx, userID, user.Name+" was removed")
+}
+
+func (uc *UserUsecase) getUser(ctx context.Context, userID uuid.UUID) (*domain.User, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	return user, nil
+}
+
+// setUserDepartment moves a user to another department. The preloaded
+// association would otherwise win over the new ID on save.
+func setUserDepartment(user *domain.User, departmentID sql.NullString) {
+	user.DepartmentID = departmentID
+	user.Department = nil
+}
@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS update_topic_department_mappings_updated_at ON topic_department_mappings;
+
+DROP INDEX IF EXISTS idx_topic_department_mappings_deleted_at;
+
+DROP INDEX IF EXISTS idx_topic_department_mappings_department_id;
+
+DROP INDEX IF EXISTS idx_topic_department_mappings_topic;
+
+DROP TABLE IF EXISTS topic_department_mappings;
@@ -0,0 +1,15 @@
+-- Map chat topics (or OSS licensing categories) to the department that handles them
+CREATE TABLE topic_department_mappings (
+    id VARCHAR(255) PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    department_id VARCHAR(255) NOT NULL REFERENCES departments(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at BIGINT DEFAULT 0 -- For soft delete support (0 = not deleted, unix timestamp = deleted)
+);
+
+CREATE UNIQUE INDEX idx_topic_department_mappings_topic ON topic_department_mappings(LOWER(topic)) WHERE deleted_at = 0;
+CREATE INDEX idx_topic_department_mappings_department_id ON topic_department_mappings(department_id);
+CREATE INDEX idx_topic_department_mappings_deleted_at ON topic_department_mappings(deleted_at);
+
+CREATE TRIGGER update_topic_department_mappings_updated_at BEFORE UPDATE ON topic_department_mappings FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_chat_messages_session_created_at;
+
+ALTER TABLE chat_logs DROP CONSTRAINT IF EXISTS chat_logs_action_check;
+ALTER TABLE chat_logs ADD CONSTRAINT chat_logs_action_check CHECK (action IN ('started', 'waiting', 'response', 'closed', 'transferred')) NOT VALID;
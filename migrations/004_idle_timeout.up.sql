@@ -0,0 +1,10 @@
+-- Allow every action the backend writes to chat_logs, including idle timeout warnings
+ALTER TABLE chat_logs DROP CONSTRAINT IF EXISTS chat_logs_action_check;
+ALTER TABLE chat_logs ADD CONSTRAINT chat_logs_action_check CHECK (action IN (
+    'started', 'waiting', 'response', 'closed', 'transferred',
+    'assigned', 'auto_assigned', 'auto_assignment_failed', 'contact_added',
+    'idle_warning'
+));
+
+-- Speeds up the last-activity lookup of the idle session sweeper
+CREATE INDEX idx_chat_messages_session_created_at ON chat_messages(session_id, created_at);
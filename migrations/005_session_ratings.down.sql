@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS update_session_ratings_updated_at ON session_ratings;
+
+DROP INDEX IF EXISTS idx_session_ratings_deleted_at;
+
+DROP INDEX IF EXISTS idx_session_ratings_created_at;
+
+DROP INDEX IF EXISTS idx_session_ratings_agent_id;
+
+DROP INDEX IF EXISTS idx_session_ratings_session_id;
+
+DROP TABLE IF EXISTS session_ratings;
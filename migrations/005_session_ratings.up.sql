@@ -0,0 +1,18 @@
+-- Customer satisfaction (CSAT) ratings, at most one per session
+CREATE TABLE session_ratings (
+    id VARCHAR(255) PRIMARY KEY,
+    session_id VARCHAR(255) NOT NULL REFERENCES chat_sessions(id),
+    agent_id VARCHAR(255) REFERENCES users(id),
+    rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    feedback TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at BIGINT DEFAULT 0 -- For soft delete support (0 = not deleted, unix timestamp = deleted)
+);
+
+CREATE UNIQUE INDEX idx_session_ratings_session_id ON session_ratings(session_id) WHERE deleted_at = 0;
+CREATE INDEX idx_session_ratings_agent_id ON session_ratings(agent_id);
+CREATE INDEX idx_session_ratings_created_at ON session_ratings(created_at);
+CREATE INDEX idx_session_ratings_deleted_at ON session_ratings(deleted_at);
+
+CREATE TRIGGER update_session_ratings_updated_at BEFORE UPDATE ON session_ratings FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
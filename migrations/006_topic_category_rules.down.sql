@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS update_topic_category_rules_updated_at ON topic_category_rules;
+
+DROP INDEX IF EXISTS idx_topic_category_rules_deleted_at;
+
+DROP INDEX IF EXISTS idx_topic_category_rules_priority;
+
+DROP TABLE IF EXISTS topic_category_rules;
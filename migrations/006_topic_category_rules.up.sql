@@ -0,0 +1,28 @@
+-- Keyword rules used to classify chat topics into OSS categories for analytics.
+-- A topic belongs to the first rule (lowest priority) whose keyword it contains.
+CREATE TABLE topic_category_rules (
+    id VARCHAR(255) PRIMARY KEY,
+    category VARCHAR(255) NOT NULL,
+    keyword VARCHAR(255) NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 100,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at BIGINT DEFAULT 0 -- For soft delete support (0 = not deleted, unix timestamp = deleted)
+);
+
+CREATE INDEX idx_topic_category_rules_priority ON topic_category_rules(priority);
+CREATE INDEX idx_topic_category_rules_deleted_at ON topic_category_rules(deleted_at);
+
+CREATE TRIGGER update_topic_category_rules_updated_at BEFORE UPDATE ON topic_category_rules FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Default OSS categories
+INSERT INTO
+    topic_category_rules (id, category, keyword, priority)
+VALUES
+    ('550e8400-e29b-41d4-a716-446655440101', 'NIB (Nomor Induk Berusaha)', 'nib', 10),
+    ('550e8400-e29b-41d4-a716-446655440102', 'NIB (Nomor Induk Berusaha)', 'nomor induk berusaha', 10),
+    ('550e8400-e29b-41d4-a716-446655440103', 'Izin Usaha Perdagangan', 'perdagangan', 20),
+    ('550e8400-e29b-41d4-a716-446655440104', 'Izin Usaha Perdagangan', 'siup', 20),
+    ('550e8400-e29b-41d4-a716-446655440105', 'Izin Usaha Industri', 'industri', 30),
+    ('550e8400-e29b-41d4-a716-446655440106', 'Izin Usaha Industri', 'iui', 30),
+    ('550e8400-e29b-41d4-a716-446655440107', 'Izin Usaha Jasa', 'jasa', 40);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_chat_analytics_rollup_key;
@@ -0,0 +1,2 @@
+-- One rollup row per day, department and agent (either may be NULL)
+CREATE UNIQUE INDEX idx_chat_analytics_rollup_key ON chat_analytics(date, COALESCE(department_id, ''), COALESCE(agent_id, '')) WHERE deleted_at = 0;
@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS update_password_reset_tokens_updated_at ON password_reset_tokens;
+
+DROP INDEX IF EXISTS idx_password_reset_tokens_deleted_at;
+
+DROP INDEX IF EXISTS idx_password_reset_tokens_user_id;
+
+DROP INDEX IF EXISTS idx_password_reset_tokens_token_hash;
+
+DROP TABLE IF EXISTS password_reset_tokens;
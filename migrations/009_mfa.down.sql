@@ -0,0 +1,19 @@
+DROP TRIGGER IF EXISTS update_mfa_policies_updated_at ON mfa_policies;
+
+DROP TABLE IF EXISTS mfa_policies;
+
+DROP TRIGGER IF EXISTS update_user_recovery_codes_updated_at ON user_recovery_codes;
+
+DROP INDEX IF EXISTS idx_user_recovery_codes_deleted_at;
+
+DROP INDEX IF EXISTS idx_user_recovery_codes_user_id;
+
+DROP TABLE IF EXISTS user_recovery_codes;
+
+DROP TRIGGER IF EXISTS update_user_mfa_updated_at ON user_mfa;
+
+DROP INDEX IF EXISTS idx_user_mfa_deleted_at;
+
+DROP INDEX IF EXISTS idx_user_mfa_user_id;
+
+DROP TABLE IF EXISTS user_mfa;
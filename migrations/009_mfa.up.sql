@@ -0,0 +1,47 @@
+-- TOTP two-factor authentication for staff users
+CREATE TABLE user_mfa (
+    id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(id),
+    secret VARCHAR(255) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    enabled_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0, -- Last accepted TOTP time step, codes cannot be replayed
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at BIGINT DEFAULT 0 -- For soft delete support (0 = not deleted, unix timestamp = deleted)
+);
+
+CREATE UNIQUE INDEX idx_user_mfa_user_id ON user_mfa(user_id) WHERE deleted_at = 0;
+CREATE INDEX idx_user_mfa_deleted_at ON user_mfa(deleted_at);
+
+CREATE TRIGGER update_user_mfa_updated_at BEFORE UPDATE ON user_mfa FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Single-use recovery codes, only the SHA-256 hash is stored
+CREATE TABLE user_recovery_codes (
+    id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users(id),
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at BIGINT DEFAULT 0 -- For soft delete support (0 = not deleted, unix timestamp = deleted)
+);
+
+CREATE INDEX idx_user_recovery_codes_user_id ON user_recovery_codes(user_id);
+CREATE INDEX idx_user_recovery_codes_deleted_at ON user_recovery_codes(deleted_at);
+
+CREATE TRIGGER update_user_recovery_codes_updated_at BEFORE UPDATE ON user_recovery_codes FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Roles whose users must use two-factor authentication
+CREATE TABLE mfa_policies (
+    role VARCHAR(50) PRIMARY KEY,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_mfa_policies_updated_at BEFORE UPDATE ON mfa_policies FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+INSERT INTO mfa_policies (role, required) VALUES
+('admin', FALSE),
+('agent', FALSE);
@@ -0,0 +1,14 @@
+DELETE FROM mfa_policies WHERE role = 'supervisor';
+
+DROP INDEX IF EXISTS idx_role_permissions_permission;
+
+DROP TABLE IF EXISTS role_permissions;
+
+DROP TRIGGER IF EXISTS update_permissions_updated_at ON permissions;
+
+DROP TABLE IF EXISTS permissions;
+
+UPDATE users SET role = 'agent' WHERE role = 'supervisor';
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('admin', 'agent'));
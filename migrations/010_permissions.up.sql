@@ -0,0 +1,40 @@
+-- Supervisors manage the sessions of their own department
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('admin', 'supervisor', 'agent'));
+
+-- Permissions that can be granted to roles
+CREATE TABLE permissions (
+    name VARCHAR(100) PRIMARY KEY,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_permissions_updated_at BEFORE UPDATE ON permissions FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE role_permissions (
+    role VARCHAR(50) NOT NULL,
+    permission VARCHAR(100) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE INDEX idx_role_permissions_permission ON role_permissions(permission);
+
+INSERT INTO permissions (name, description) VALUES
+('sessions:view', 'View waiting, active and past chat sessions'),
+('sessions:assign', 'Assign and transfer chat sessions to agents'),
+('sessions:close', 'Close chat sessions of other agents'),
+('departments:all', 'Act on all departments instead of only the own department');
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'sessions:view'),
+('admin', 'sessions:assign'),
+('admin', 'sessions:close'),
+('admin', 'departments:all'),
+('supervisor', 'sessions:view'),
+('supervisor', 'sessions:assign'),
+('supervisor', 'sessions:close');
+
+INSERT INTO mfa_policies (role, required) VALUES
+('supervisor', FALSE);
@@ -0,0 +1,8 @@
+DELETE FROM permissions WHERE name = 'sessions:override';
+
+ALTER TABLE chat_logs DROP CONSTRAINT IF EXISTS chat_logs_action_check;
+ALTER TABLE chat_logs ADD CONSTRAINT chat_logs_action_check CHECK (action IN (
+    'started', 'waiting', 'response', 'closed', 'transferred',
+    'assigned', 'auto_assigned', 'auto_assignment_failed', 'contact_added',
+    'idle_warning'
+)) NOT VALID;
@@ -0,0 +1,14 @@
+-- Admins and supervisors acting on sessions assigned to someone else are audited in chat_logs
+ALTER TABLE chat_logs DROP CONSTRAINT IF EXISTS chat_logs_action_check;
+ALTER TABLE chat_logs ADD CONSTRAINT chat_logs_action_check CHECK (action IN (
+    'started', 'waiting', 'response', 'closed', 'transferred',
+    'assigned', 'auto_assigned', 'auto_assignment_failed', 'contact_added',
+    'idle_warning', 'access_override'
+));
+
+INSERT INTO permissions (name, description) VALUES
+('sessions:override', 'Act on sessions assigned to other agents through the agent endpoints, audited');
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'sessions:override'),
+('supervisor', 'sessions:override');
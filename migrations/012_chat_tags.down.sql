@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_chat_session_tags_session_tag;
+ALTER TABLE chat_session_tags ADD CONSTRAINT chat_session_tags_session_id_tag_id_key UNIQUE (session_id, tag_id);
+
+DROP INDEX IF EXISTS idx_chat_tags_name;
+ALTER TABLE chat_tags ADD CONSTRAINT chat_tags_name_key UNIQUE (name);
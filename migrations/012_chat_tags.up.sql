@@ -0,0 +1,7 @@
+-- Tag names and session tags only have to be unique among rows that are not
+-- soft-deleted, so deleted tags can be recreated and removed tags re-added
+ALTER TABLE chat_tags DROP CONSTRAINT IF EXISTS chat_tags_name_key;
+CREATE UNIQUE INDEX idx_chat_tags_name ON chat_tags(LOWER(name)) WHERE deleted_at = 0;
+
+ALTER TABLE chat_session_tags DROP CONSTRAINT IF EXISTS chat_session_tags_session_id_tag_id_key;
+CREATE UNIQUE INDEX idx_chat_session_tags_session_tag ON chat_session_tags(session_id, tag_id) WHERE deleted_at = 0;
@@ -0,0 +1,14 @@
+-- Full-text search over message bodies. Messages are indexed with both the
+-- Indonesian and the English stemmer so searches in either language match.
+ALTER TABLE chat_messages ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('indonesian', message) || to_tsvector('english', message)) STORED;
+
+CREATE INDEX idx_chat_messages_search_vector ON chat_messages USING GIN (search_vector);
+
+INSERT INTO permissions (name, description) VALUES
+('messages:search', 'Search the messages of past conversations');
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'messages:search'),
+('supervisor', 'messages:search'),
+('agent', 'messages:search');
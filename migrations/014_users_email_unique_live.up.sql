@@ -0,0 +1,4 @@
+-- User emails only have to be unique among users that are not soft-deleted,
+-- so the email of a deleted user can be registered again
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+CREATE UNIQUE INDEX idx_users_email_live ON users(email) WHERE deleted_at = 0;
@@ -0,0 +1 @@
+ALTER TABLE chat_analytics DROP COLUMN IF EXISTS responded_sessions;
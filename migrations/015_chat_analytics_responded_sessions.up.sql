@@ -0,0 +1,7 @@
+-- average_response_time is averaged over the sessions that got an agent
+-- reply, so reports weight it by their number rather than by total_sessions
+ALTER TABLE chat_analytics ADD COLUMN responded_sessions INTEGER NOT NULL DEFAULT 0;
+
+-- Older rows did not record it. Days still in the rollup window are
+-- recomputed; for the rest keep the previous weighting.
+UPDATE chat_analytics SET responded_sessions = total_sessions WHERE average_response_time > 0;
@@ -0,0 +1 @@
+ALTER TABLE session_ratings DROP COLUMN IF EXISTS relayed_by;
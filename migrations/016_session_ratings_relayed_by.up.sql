@@ -0,0 +1,3 @@
+-- Staff user who entered a customer's rating on their behalf when closing the
+-- session; NULL when the customer rated directly
+ALTER TABLE session_ratings ADD COLUMN relayed_by VARCHAR(255) REFERENCES users(id);
@@ -4,20 +4,27 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Database  DatabaseConfig
-	Redis     RedisConfig
-	Server    ServerConfig
-	JWT       JWTConfig
-	WebSocket WebSocketConfig
-	Email     EmailConfig
-	App       AppConfig
-	Kafka     KafkaConfig
+	Database   DatabaseConfig
+	Redis      RedisConfig
+	Server     ServerConfig
+	JWT        JWTConfig
+	WebSocket  WebSocketConfig
+	Email      EmailConfig
+	App        AppConfig
+	Kafka      KafkaConfig
+	Assignment AssignmentConfig
+	Idle       IdleConfig
+	Analytics  AnalyticsConfig
+	Login      LoginConfig
+	Auth       AuthConfig
+	OIDC       OIDCConfig
 }
 
 type DatabaseConfig struct {
@@ -52,9 +59,10 @@ type WebSocketConfig struct {
 }
 
 type EmailConfig struct {
-	SendGridAPIKey string
-	FromEmail      string
-	FromName       string
+	SendGridAPIKey   string
+	FromEmail        string
+	FromName         string
+	PasswordResetURL string // frontend page the reset token is appended to as ?token=
 }
 
 type AppConfig struct {
@@ -66,6 +74,60 @@ type KafkaConfig struct {
 	Topic  string
 }
 
+type AssignmentConfig struct {
+	Strategy            string             // least_active, round_robin or department_weighted
+	MaxSessionsPerAgent int                // 0 disables the cap
+	DepartmentWeights   map[string]float64 // department ID -> weight
+	RetryInterval       time.Duration      // how often waiting sessions are re-assigned
+}
+
+type IdleConfig struct {
+	WaitingTimeout time.Duration // 0 disables auto-close of waiting sessions
+	ActiveTimeout  time.Duration // 0 disables auto-close of active sessions
+	WarningBefore  time.Duration // how long before closing the customer is warned
+	SweepInterval  time.Duration
+}
+
+type AnalyticsConfig struct {
+	RollupTime time.Duration // time of day the nightly rollup runs, as offset from midnight
+}
+
+type LoginConfig struct {
+	MaxAttempts   int           // failed logins per email before lockouts start, 0 disables
+	IPMaxAttempts int           // failed logins per client IP before lockouts start, 0 disables
+	FailureWindow time.Duration // failures older than this are forgotten
+	BaseLockout   time.Duration // first lockout, doubled for every further failure
+	MaxLockout    time.Duration
+}
+
+type AuthConfig struct {
+	PasswordResetTokenTTL time.Duration
+	MFAIssuer             string        // name shown in authenticator apps
+	MFAChallengeTTL       time.Duration // how long the second login step may take
+	CustomerTokenTTL      time.Duration // lifetime of chat widget tokens, renewable while the chat is open
+}
+
+// OIDCConfig configures staff single sign-on. SSO is off unless an issuer and
+// client ID are set.
+type OIDCConfig struct {
+	IssuerURL            string
+	ClientID             string
+	ClientSecret         string // empty for public clients, PKCE protects the code
+	RedirectURL          string // frontend page the IdP sends code and state to
+	Scopes               []string
+	EmailClaim           string
+	GroupsClaim          string
+	RoleMapping          map[string]string // IdP group -> role
+	DepartmentMapping    map[string]string // IdP group -> department ID
+	JITProvisioning      bool              // create users on their first SSO login
+	StateTTL             time.Duration     // how long a started SSO login may take
+	AllowUnverifiedEmail bool              // for IdPs that never send email_verified
+}
+
+func (c OIDCConfig) Enabled() bool {
+	return c.IssuerURL != "" && c.ClientID != ""
+}
+
 func LoadConfig() *Config {
 	if err := godotenv.Load(); err != nil {
 		log.Println("Warning: .env file not found")
@@ -97,6 +159,21 @@ func LoadConfig() *Config {
 		redisDB = 0
 	}
 
+	maxSessionsPerAgent, err := strconv.Atoi(getEnv("ASSIGNMENT_MAX_SESSIONS_PER_AGENT", "5"))
+	if err != nil {
+		maxSessionsPerAgent = 5
+	}
+
+	loginMaxAttempts, err := strconv.Atoi(getEnv("LOGIN_MAX_ATTEMPTS", "5"))
+	if err != nil {
+		loginMaxAttempts = 5
+	}
+
+	loginIPMaxAttempts, err := strconv.Atoi(getEnv("LOGIN_IP_MAX_ATTEMPTS", "20"))
+	if err != nil {
+		loginIPMaxAttempts = 20
+	}
+
 	return &Config{
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -125,9 +202,10 @@ func LoadConfig() *Config {
 			WriteBufferSize: writeBufferSize,
 		},
 		Email: EmailConfig{
-			SendGridAPIKey: getEnv("SENDGRID_API_KEY", ""),
-			FromEmail:      getEnv("SENDGRID_FROM_EMAIL", "noreply@yourcompany.com"),
-			FromName:       getEnv("SENDGRID_FROM_NAME", "LiveChat System"),
+			SendGridAPIKey:   getEnv("SENDGRID_API_KEY", ""),
+			FromEmail:        getEnv("SENDGRID_FROM_EMAIL", "noreply@yourcompany.com"),
+			FromName:         getEnv("SENDGRID_FROM_NAME", "LiveChat System"),
+			PasswordResetURL: getEnv("PASSWORD_RESET_URL", "https://yourapp.com/reset-password"),
 		},
 		App: AppConfig{
 			Environment: getEnv("APP_ENV", "development"),
@@ -136,6 +214,48 @@ func LoadConfig() *Config {
 			Broker: getEnv("KAFKA_BROKER", "localhost:9092"),
 			Topic:  getEnv("KAFKA_TOPIC", "chat-messages"),
 		},
+		Assignment: AssignmentConfig{
+			Strategy:            getEnv("ASSIGNMENT_STRATEGY", "least_active"),
+			MaxSessionsPerAgent: maxSessionsPerAgent,
+			DepartmentWeights:   parseWeights(getEnv("ASSIGNMENT_DEPARTMENT_WEIGHTS", "")),
+			RetryInterval:       getEnvDuration("ASSIGNMENT_RETRY_INTERVAL", 30*time.Second),
+		},
+		Idle: IdleConfig{
+			WaitingTimeout: getEnvDuration("IDLE_WAITING_TIMEOUT", 30*time.Minute),
+			ActiveTimeout:  getEnvDuration("IDLE_ACTIVE_TIMEOUT", 15*time.Minute),
+			WarningBefore:  getEnvDuration("IDLE_WARNING_BEFORE", 2*time.Minute),
+			SweepInterval:  getEnvDuration("IDLE_SWEEP_INTERVAL", time.Minute),
+		},
+		Analytics: AnalyticsConfig{
+			RollupTime: getEnvTimeOfDay("ANALYTICS_ROLLUP_TIME", time.Hour),
+		},
+		Login: LoginConfig{
+			MaxAttempts:   loginMaxAttempts,
+			IPMaxAttempts: loginIPMaxAttempts,
+			FailureWindow: getEnvDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+			BaseLockout:   getEnvDuration("LOGIN_BASE_LOCKOUT", 30*time.Second),
+			MaxLockout:    getEnvDuration("LOGIN_MAX_LOCKOUT", 15*time.Minute),
+		},
+		Auth: AuthConfig{
+			PasswordResetTokenTTL: getEnvDuration("PASSWORD_RESET_TOKEN_TTL", time.Hour),
+			MFAIssuer:             getEnv("MFA_ISSUER", "LiveChat"),
+			MFAChallengeTTL:       getEnvDuration("MFA_CHALLENGE_TTL", 5*time.Minute),
+			CustomerTokenTTL:      getEnvDuration("CUSTOMER_TOKEN_TTL", time.Hour),
+		},
+		OIDC: OIDCConfig{
+			IssuerURL:            getEnv("OIDC_ISSUER_URL", ""),
+			ClientID:             getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret:         getEnv("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:          getEnv("OIDC_REDIRECT_URL", "http://localhost:3000/auth/sso/callback"),
+			Scopes:               strings.Fields(getEnv("OIDC_SCOPES", "openid email profile")),
+			EmailClaim:           getEnv("OIDC_EMAIL_CLAIM", "email"),
+			GroupsClaim:          getEnv("OIDC_GROUPS_CLAIM", "groups"),
+			RoleMapping:          parseMapping(getEnv("OIDC_ROLE_MAPPING", "")),
+			DepartmentMapping:    parseMapping(getEnv("OIDC_DEPARTMENT_MAPPING", "")),
+			JITProvisioning:      getEnvBool("OIDC_JIT_PROVISIONING", false),
+			AllowUnverifiedEmail: getEnvBool("OIDC_ALLOW_UNVERIFIED_EMAIL", false),
+			StateTTL:             getEnvDuration("OIDC_STATE_TTL", 10*time.Minute),
+		},
 	}
 }
 
@@ -145,3 +265,70 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvBool parses "true"/"false" style values, falling back to the default
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(getEnv(key, strconv.FormatBool(defaultValue)))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvDuration parses a duration such as "30s" or "15m", falling back to the default
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	duration, err := time.ParseDuration(getEnv(key, defaultValue.String()))
+	if err != nil {
+		return defaultValue
+	}
+	return duration
+}
+
+// getEnvTimeOfDay parses a "HH:MM" time of day into an offset from midnight,
+// falling back to the default
+func getEnvTimeOfDay(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return defaultValue
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+// parseWeights parses "key:weight,key:weight" pairs, skipping malformed
+// entries and weights that are not positive
+func parseWeights(value string) map[string]float64 {
+	weights := make(map[string]float64)
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		weight, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || weight <= 0 {
+			continue
+		}
+		weights[parts[0]] = weight
+	}
+	return weights
+}
+
+// parseMapping parses "key:value,key:value" pairs, skipping malformed entries.
+// Keys are split at the last colon so they may contain colons themselves.
+func parseMapping(value string) map[string]string {
+	mapping := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		i := strings.LastIndex(pair, ":")
+		if i <= 0 || i == len(pair)-1 {
+			continue
+		}
+		mapping[pair[:i]] = pair[i+1:]
+	}
+	return mapping
+}
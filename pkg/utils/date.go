@@ -0,0 +1,19 @@
+package utils
+
+import "time"
+
+// DateLayout is the YYYY-MM-DD format of date query parameters
+const DateLayout = "2006-01-02"
+
+// StartOfDay returns local midnight of the calendar day of t. Analytics days
+// and the nightly jobs both use local days, so a DATE read back from the
+// database (midnight UTC) maps to the same day.
+func StartOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+}
+
+// ParseDate parses a YYYY-MM-DD date as local midnight
+func ParseDate(value string) (time.Time, error) {
+	return time.ParseInLocation(DateLayout, value, time.Local)
+}
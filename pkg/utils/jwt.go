@@ -13,15 +13,36 @@ type JWTClaims struct {
 	Email        string  `json:"email"`
 	Role         string  `json:"role"`
 	DepartmentID *string `json:"department_id"`
-	TokenType    string  `json:"token_type"` // "access" or "refresh"
+	TokenType    string  `json:"token_type"`           // "access", "refresh", "mfa" or "customer"
+	FamilyID     string  `json:"family_id,omitempty"`  // refresh token family both tokens belong to
+	SessionID    string  `json:"session_id,omitempty"` // chat session a customer token is bound to
+	IssuedAtMs   int64   `json:"iat_ms,omitempty"`     // issue time of access and refresh tokens in Unix milliseconds
 	jwt.RegisteredClaims
 }
 
+// IssuedAtMilli returns the issue time of the token in Unix milliseconds.
+// Tokens issued before iat_ms existed fall back to the start of their iat
+// second, and tokens without any issue time to 0.
+func (c *JWTClaims) IssuedAtMilli() int64 {
+	if c.IssuedAtMs > 0 {
+		return c.IssuedAtMs
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.Time.UnixMilli()
+	}
+	return 0
+}
+
 type TokenPair struct {
 	AccessToken  string    `json:"access_token"`
 	RefreshToken string    `json:"refresh_token"`
 	ExpiresIn    int64     `json:"expires_in"`
 	ExpiresAt    time.Time `json:"expires_at"`
+
+	// Server-side bookkeeping of the refresh token, not sent to clients
+	FamilyID         string    `json:"-"`
+	RefreshTokenID   string    `json:"-"`
+	RefreshExpiresAt time.Time `json:"-"`
 }
 
 type JWTUtil struct {
@@ -38,8 +59,18 @@ func NewJWTUtil(secret string, accessTokenDuration, refreshTokenDuration time.Du
 	}
 }
 
-func (j *JWTUtil) GenerateTokenPair(userID string, email, role string, departmentID *string) (*TokenPair, error) {
+// RefreshTokenDuration is the longest lifetime of any token issued
+func (j *JWTUtil) RefreshTokenDuration() time.Duration {
+	return j.refreshTokenDuration
+}
+
+// GenerateTokenPair issues an access and a refresh token. Both carry the
+// refresh token family; an empty familyID starts a new family.
+func (j *JWTUtil) GenerateTokenPair(userID string, email, role string, departmentID *string, familyID string) (*TokenPair, error) {
 	now := time.Now()
+	if familyID == "" {
+		familyID = uuid.New().String()
+	}
 
 	// Generate Access Token (15 minutes)
 	accessExpirationTime := now.Add(j.accessTokenDuration)
@@ -49,12 +80,14 @@ func (j *JWTUtil) GenerateTokenPair(userID string, email, role string, departmen
 		Role:         role,
 		DepartmentID: departmentID,
 		TokenType:    "access",
+		FamilyID:     familyID,
+		IssuedAtMs:   now.UnixMilli(),
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(accessExpirationTime),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Subject:   userID,
-			ID:        uuid.New().String(),
+			ID:        uuid.New().String(), // jti, used to revoke the token
 		},
 	}
 
@@ -72,12 +105,14 @@ func (j *JWTUtil) GenerateTokenPair(userID string, email, role string, departmen
 		Role:         role,
 		DepartmentID: departmentID,
 		TokenType:    "refresh",
+		FamilyID:     familyID,
+		IssuedAtMs:   now.UnixMilli(),
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(refreshExpirationTime),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Subject:   userID,
-			ID:        uuid.New().String(),
+			ID:        uuid.New().String(), // jti, used to revoke the token
 		},
 	}
 
@@ -88,13 +123,92 @@ func (j *JWTUtil) GenerateTokenPair(userID string, email, role string, departmen
 	}
 
 	return &TokenPair{
-		AccessToken:  accessTokenString,
-		RefreshToken: refreshTokenString,
-		ExpiresIn:    int64(j.accessTokenDuration.Seconds()),
-		ExpiresAt:    accessExpirationTime,
+		AccessToken:      accessTokenString,
+		RefreshToken:     refreshTokenString,
+		ExpiresIn:        int64(j.accessTokenDuration.Seconds()),
+		ExpiresAt:        accessExpirationTime,
+		FamilyID:         familyID,
+		RefreshTokenID:   refreshClaims.ID,
+		RefreshExpiresAt: refreshExpirationTime,
 	}, nil
 }
 
+// GenerateMFAToken issues a short-lived token proving the password step of a
+// login succeeded. It is only accepted to complete the second factor.
+func (j *JWTUtil) GenerateMFAToken(userID string, duration time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(duration)
+	claims := &JWTClaims{
+		UserID:    userID,
+		TokenType: "mfa",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Subject:   userID,
+			ID:        uuid.New().String(),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(j.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+func (j *JWTUtil) ValidateMFAToken(tokenString string) (*JWTClaims, error) {
+	claims, err := j.validateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != "mfa" {
+		return nil, errors.New("invalid token type: expected mfa token")
+	}
+
+	return claims, nil
+}
+
+// GenerateCustomerToken issues a short-lived token for the chat widget. UserID
+// holds the chat user, not a staff user, and the token is only valid for the
+// given chat session.
+func (j *JWTUtil) GenerateCustomerToken(chatUserID, sessionID string, duration time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(duration)
+	claims := &JWTClaims{
+		UserID:    chatUserID,
+		TokenType: "customer",
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Subject:   chatUserID,
+			ID:        uuid.New().String(),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(j.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+func (j *JWTUtil) ValidateCustomerToken(tokenString string) (*JWTClaims, error) {
+	claims, err := j.validateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != "customer" {
+		return nil, errors.New("invalid token type: expected customer token")
+	}
+
+	return claims, nil
+}
+
 func (j *JWTUtil) ValidateAccessToken(tokenString string) (*JWTClaims, error) {
 	claims, err := j.validateToken(tokenString)
 	if err != nil {
@@ -146,17 +260,6 @@ func (j *JWTUtil) validateToken(tokenString string) (*JWTClaims, error) {
 	return claims, nil
 }
 
-func (j *JWTUtil) RefreshTokenPair(refreshTokenString string) (*TokenPair, error) {
-	// Validate refresh token
-	claims, err := j.ValidateRefreshToken(refreshTokenString)
-	if err != nil {
-		return nil, err
-	}
-
-	// Generate new token pair
-	return j.GenerateTokenPair(claims.UserID, claims.Email, claims.Role, claims.DepartmentID)
-}
-
 // Legacy support - untuk backward compatibility
 func (j *JWTUtil) GenerateToken(userID uuid.UUID, email, role string, departmentID *uuid.UUID) (string, time.Time, error) {
 	var deptIDStr *string
@@ -164,7 +267,7 @@ func (j *JWTUtil) GenerateToken(userID uuid.UUID, email, role string, department
 		deptStr := departmentID.String()
 		deptIDStr = &deptStr
 	}
-	tokenPair, err := j.GenerateTokenPair(userID.String(), email, role, deptIDStr)
+	tokenPair, err := j.GenerateTokenPair(userID.String(), email, role, deptIDStr, "")
 	if err != nil {
 		return "", time.Time{}, err
 	}
@@ -174,11 +277,3 @@ func (j *JWTUtil) GenerateToken(userID uuid.UUID, email, role string, department
 func (j *JWTUtil) ValidateToken(tokenString string) (*JWTClaims, error) {
 	return j.ValidateAccessToken(tokenString)
 }
-
-func (j *JWTUtil) RefreshToken(tokenString string) (string, time.Time, error) {
-	tokenPair, err := j.RefreshTokenPair(tokenString)
-	if err != nil {
-		return "", time.Time{}, err
-	}
-	return tokenPair.AccessToken, tokenPair.ExpiresAt, nil
-}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestTokenPairCarriesMillisecondIssueTime(t *testing.T) {
+	j := NewJWTUtil("secret", 15*time.Minute, 24*time.Hour)
+
+	before := time.Now().UnixMilli()
+	pair, err := j.GenerateTokenPair("user-1", "user@example.com", "agent", nil, "")
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+	after := time.Now().UnixMilli()
+
+	access, err := j.ValidateAccessToken(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken: %v", err)
+	}
+	refresh, err := j.ValidateRefreshToken(pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("ValidateRefreshToken: %v", err)
+	}
+
+	for name, claims := range map[string]*JWTClaims{"access": access, "refresh": refresh} {
+		if got := claims.IssuedAtMilli(); got < before || got > after {
+			t.Errorf("%s: IssuedAtMilli = %d, want between %d and %d", name, got, before, after)
+		}
+	}
+}
+
+func TestIssuedAtMilliFallsBackToIat(t *testing.T) {
+	issued := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name   string
+		claims JWTClaims
+		want   int64
+	}{
+		{name: "iat_ms", claims: JWTClaims{IssuedAtMs: 1700000000123, RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(issued)}}, want: 1700000000123},
+		{name: "only iat", claims: JWTClaims{RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(issued)}}, want: 1700000000000},
+		{name: "no issue time", want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.claims.IssuedAtMilli(); got != tt.want {
+			t.Errorf("%s: IssuedAtMilli = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// GenerateRandomBytes reads the given number of bytes from crypto/rand
+func GenerateRandomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// GenerateSecureToken returns a random hex token built from the given number
+// of random bytes
+func GenerateSecureToken(bytes int) (string, error) {
+	b, err := GenerateRandomBytes(bytes)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken returns the hex SHA-256 of a token. Tokens that are random and
+// long enough do not need a slow hash like passwords do.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
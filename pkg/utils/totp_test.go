@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed of RFC 6238 Appendix B, "12345678901234567890",
+// base32 encoded
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// rfc6238Vectors are the SHA-1 test vectors of RFC 6238 Appendix B. The RFC
+// lists 8-digit codes; a 6-digit code is the last six digits of those.
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},          // 94287082
+	{1111111109, "081804"},  // 07081804
+	{1111111111, "050471"},  // 14050471
+	{1234567890, "005924"},  // 89005924
+	{2000000000, "279037"},  // 69279037
+	{20000000000, "353130"}, // 65353130
+}
+
+func TestTOTPCode(t *testing.T) {
+	for _, tt := range rfc6238Vectors {
+		code, err := TOTPCode(rfc6238Secret, tt.unix/totpPeriod)
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", tt.unix, err)
+		}
+		if code != tt.code {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestTOTPCodeLowercaseSecret(t *testing.T) {
+	code, err := TOTPCode("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", 59/totpPeriod)
+	if err != nil {
+		t.Fatalf("TOTPCode: %v", err)
+	}
+	if code != "287082" {
+		t.Errorf("TOTPCode = %s, want 287082", code)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	for _, tt := range rfc6238Vectors {
+		at := time.Unix(tt.unix, 0)
+		step, ok := ValidateTOTP(rfc6238Secret, tt.code, at)
+		if !ok {
+			t.Errorf("ValidateTOTP(%d) rejected %s", tt.unix, tt.code)
+			continue
+		}
+		if step != tt.unix/totpPeriod {
+			t.Errorf("ValidateTOTP(%d) step = %d, want %d", tt.unix, step, tt.unix/totpPeriod)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	const unix = 1234567890
+	step := int64(unix / totpPeriod)
+
+	tests := []struct {
+		name   string
+		offset int64
+		want   bool
+	}{
+		{name: "previous period", offset: -1, want: true},
+		{name: "current period", offset: 0, want: true},
+		{name: "next period", offset: 1, want: true},
+		{name: "two periods ago", offset: -2, want: false},
+		{name: "two periods ahead", offset: 2, want: false},
+	}
+
+	for _, tt := range tests {
+		code, err := TOTPCode(rfc6238Secret, step+tt.offset)
+		if err != nil {
+			t.Fatalf("%s: TOTPCode: %v", tt.name, err)
+		}
+
+		got, ok := ValidateTOTP(rfc6238Secret, code, time.Unix(unix, 0))
+		if ok != tt.want {
+			t.Errorf("%s: ValidateTOTP = %v, want %v", tt.name, ok, tt.want)
+		}
+		if ok && got != step+tt.offset {
+			t.Errorf("%s: step = %d, want %d", tt.name, got, step+tt.offset)
+		}
+	}
+}
+
+func TestValidateTOTPRejectsMalformedCodes(t *testing.T) {
+	at := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870822", "94287082"} {
+		if _, ok := ValidateTOTP(rfc6238Secret, code, at); ok {
+			t.Errorf("ValidateTOTP accepted %q", code)
+		}
+	}
+}